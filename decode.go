@@ -16,11 +16,13 @@
 package yaml
 
 import (
+	"context"
 	"encoding"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"math"
+	"os"
 	"reflect"
 	"time"
 
@@ -40,6 +42,10 @@ type parser struct {
 	parentAnchors map[string]struct{}
 	doneInit      bool
 	textless      bool
+
+	maxScalarLength int
+	maxNodes        int
+	nodes           int
 }
 
 func newParser(b []byte) *parser {
@@ -57,6 +63,13 @@ func newParserFromReader(r io.Reader) *parser {
 	return p
 }
 
+func (p *parser) setLimits(l Limits) {
+	p.parser.max_depth = l.MaxDepth
+	p.parser.max_bytes = l.MaxBytes
+	p.maxScalarLength = l.MaxScalarLength
+	p.maxNodes = l.MaxNodes
+}
+
 func (p *parser) init() {
 	if p.doneInit {
 		return
@@ -144,7 +157,25 @@ func (p *parser) fail() {
 		msg = "unknown problem parsing YAML content"
 	}
 
-	fail(syntaxErr(offset, line, column, msg))
+	fail(&SyntaxError{
+		Offset: offset,
+		Line:   line,
+		Column: column,
+		Msg:    msg,
+		Err:    p.parser.problem_err,
+	})
+}
+
+// failLimit fails with a limit error at the current event.
+func (p *parser) failLimit(err error, msgf string, args ...any) {
+	mark := p.event.start_mark
+	fail(&SyntaxError{
+		Offset: mark.index,
+		Line:   mark.line + 1,
+		Column: mark.column + 1,
+		Msg:    fmt.Sprintf(msgf, args...),
+		Err:    err,
+	})
 }
 
 func (p *parser) anchor(n *Node, anchor []byte) {
@@ -178,6 +209,10 @@ func (p *parser) parse() *Node {
 }
 
 func (p *parser) node(kind Kind, defaultTag, tag, value string) *Node {
+	p.nodes++
+	if p.maxNodes > 0 && p.nodes > p.maxNodes {
+		p.failLimit(ErrMaxNodes, "exceeded max node count of %d", p.maxNodes)
+	}
 	var style Style
 	switch {
 	case tag != "" && tag != "!":
@@ -211,6 +246,7 @@ func (p *parser) parseChild(parent *Node) *Node {
 }
 
 func (p *parser) document() *Node {
+	p.nodes = 0
 	n := p.node(DocumentNode, "", "", "")
 	p.doc = n
 	p.expect(yaml_DOCUMENT_START_EVENT)
@@ -237,6 +273,9 @@ func (p *parser) alias() *Node {
 }
 
 func (p *parser) scalar() *Node {
+	if p.maxScalarLength > 0 && len(p.event.value) > p.maxScalarLength {
+		p.failLimit(ErrMaxScalarLength, "exceeded max scalar length of %d", p.maxScalarLength)
+	}
 	parsedStyle := p.event.scalar_style()
 	var nodeStyle Style
 	switch {
@@ -344,15 +383,26 @@ func (p *parser) mapping() *Node {
 type decoder struct {
 	doc     *Node
 	terrors []error
+	ctx     context.Context
 
 	stringMapType  reflect.Type
 	generalMapType reflect.Type
 
-	knownFields bool
-	uniqueKeys  bool
-	decodeCount int
-	aliasCount  int
-	aliasDepth  int
+	knownFields     bool
+	caseInsensitive bool
+	orderedMaps     bool
+	duplicateKeys   DuplicateKeyPolicy
+	jsonTags        bool
+	tags            map[string]TagDecodeFunc
+	lookupEnv       func(key string) (string, bool)
+	onWarning       func(err error)
+	maxAliases      int
+	decodeCount     int
+	aliasCount      int
+	aliasDepth      int
+
+	// path is the path of the node being decoded.
+	path Path
 
 	mergedFields map[any]struct{}
 }
@@ -366,15 +416,47 @@ var (
 	ifaceType      = generalMapType.Elem()
 )
 
-func newDecoder() *decoder {
+func newDecoder(opts DecodeOptions) *decoder {
 	d := &decoder{
-		stringMapType:  stringMapType,
-		generalMapType: generalMapType,
-		uniqueKeys:     true,
+		ctx:             context.Background(),
+		stringMapType:   stringMapType,
+		generalMapType:  generalMapType,
+		knownFields:     opts.KnownFields,
+		caseInsensitive: opts.CaseInsensitive,
+		orderedMaps:     opts.OrderedMaps,
+		duplicateKeys:   opts.DuplicateKeys,
+		jsonTags:        opts.JSONTags,
+		tags:            tagDecoders(opts.Tags),
+		onWarning:       opts.OnWarning,
+		maxAliases:      opts.Limits.MaxAliases,
+	}
+	if opts.ExpandEnv {
+		d.lookupEnv = opts.LookupEnv
+		if d.lookupEnv == nil {
+			d.lookupEnv = os.LookupEnv
+		}
 	}
 	return d
 }
 
+// tagDecoders returns a copy of given tag decoders with normalized tags.
+func tagDecoders(tags map[string]TagDecodeFunc) map[string]TagDecodeFunc {
+	if len(tags) == 0 {
+		return nil
+	}
+	r := make(map[string]TagDecodeFunc, len(tags))
+	for tag, f := range tags {
+		r[shortTag(tag)] = f
+	}
+	return r
+}
+
+func (d *decoder) warn(err error) {
+	if d.onWarning != nil {
+		d.onWarning(err)
+	}
+}
+
 func (d *decoder) terror(n *Node, tag string, out reflect.Value) {
 	if n.Tag != "" {
 		tag = n.Tag
@@ -389,9 +471,12 @@ func (d *decoder) terror(n *Node, tag string, out reflect.Value) {
 	}
 
 	typ := out.Type()
-	d.terrors = append(d.terrors,
-		unmarshalErrf(n, typ, "cannot unmarshal %s%s into %s", shortTag(tag), value, typ),
-	)
+	d.terrors = append(d.terrors, &UnmarshalError{
+		Node: n,
+		Type: typ,
+		Path: d.currentPath(),
+		Err:  errors.Errorf("cannot unmarshal %s%s into %s", shortTag(tag), value, typ),
+	})
 }
 
 func (d *decoder) mapCustomError(err error) bool {
@@ -407,13 +492,59 @@ func (d *decoder) mapCustomError(err error) bool {
 }
 
 func (d *decoder) callUnmarshaler(n *Node, u Unmarshaler) (good bool) {
-	return d.mapCustomError(u.UnmarshalYAML(n))
+	return d.mapCustomError(d.nestedErr(u.UnmarshalYAML(n)))
+}
+
+func (d *decoder) callUnmarshalerContext(n *Node, u UnmarshalerContext) (good bool) {
+	return d.mapCustomError(d.nestedErr(u.UnmarshalYAML(d.ctx, n)))
+}
+
+func (d *decoder) callTagDecoder(n *Node, out reflect.Value, f TagDecodeFunc) (good bool) {
+	for out.Kind() == reflect.Ptr {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	if out.Kind() == reflect.Interface && out.NumMethod() == 0 {
+		// Pass a value of the default type, since an interface value
+		// can't be set like a concrete one.
+		v := reflect.New(d.defaultType(n)).Elem()
+		good = d.mapCustomError(d.nestedErr(f(n, v)))
+		out.Set(v)
+		return good
+	}
+	return d.mapCustomError(d.nestedErr(f(n, out)))
+}
+
+// defaultType returns the type of the value a node with an unknown tag is
+// decoded to in an empty interface.
+func (d *decoder) defaultType(n *Node) reflect.Type {
+	switch n.Kind {
+	case MappingNode:
+		switch {
+		case d.orderedMaps:
+			return mapSliceType
+		case isStringMap(n):
+			return d.stringMapType
+		default:
+			return d.generalMapType
+		}
+	case SequenceNode:
+		return reflect.TypeOf([]any{})
+	default:
+		return reflect.TypeOf("")
+	}
 }
 
 func (d *decoder) callObsoleteUnmarshaler(n *Node, u obsoleteUnmarshaler) (good bool) {
 	terrlen := len(d.terrors)
+	depth := len(d.path)
 	err := u.UnmarshalYAML(func(v any) (err error) {
 		defer handleErr(&err)
+		defer func() {
+			d.path = d.path[:depth]
+		}()
 		d.unmarshal(n, reflect.ValueOf(v))
 		if len(d.terrors) > terrlen {
 			issues := d.terrors[terrlen:]
@@ -452,6 +583,10 @@ func (d *decoder) prepare(n *Node, out reflect.Value) (newout reflect.Value, unm
 		}
 		if out.CanAddr() {
 			outi := out.Addr().Interface()
+			if u, ok := outi.(UnmarshalerContext); ok {
+				good = d.callUnmarshalerContext(n, u)
+				return out, true, good
+			}
 			if u, ok := outi.(Unmarshaler); ok {
 				good = d.callUnmarshaler(n, u)
 				return out, true, good
@@ -516,11 +651,20 @@ func allowedAliasRatio(decodeCount int) float64 {
 
 func (d *decoder) unmarshal(n *Node, out reflect.Value) (good bool) {
 	d.decodeCount++
+	if d.decodeCount%contextCheckInterval == 0 {
+		if err := d.ctx.Err(); err != nil {
+			fail(err)
+		}
+	}
 	if d.aliasDepth > 0 {
 		d.aliasCount++
 	}
-	if d.aliasCount > 100 && d.decodeCount > 1000 && float64(d.aliasCount)/float64(d.decodeCount) > allowedAliasRatio(d.decodeCount) {
-		fail(unmarshalErrf(n, out.Type(), "document contains excessive aliasing"))
+	if d.maxAliases > 0 {
+		if d.aliasCount > d.maxAliases {
+			d.fail(&UnmarshalError{Node: n, Type: out.Type(), Err: ErrMaxAliases})
+		}
+	} else if d.aliasCount > 100 && d.decodeCount > 1000 && float64(d.aliasCount)/float64(d.decodeCount) > allowedAliasRatio(d.decodeCount) {
+		d.fail(&UnmarshalError{Node: n, Type: out.Type(), Err: ErrMaxAliases})
 	}
 	switch out.Type() {
 	case nodeType:
@@ -536,6 +680,12 @@ func (d *decoder) unmarshal(n *Node, out reflect.Value) (good bool) {
 	case AliasNode:
 		return d.alias(n, out)
 	}
+	if n.Kind == ScalarNode && d.lookupEnv != nil {
+		n = d.expandEnv(n, out)
+	}
+	if f, ok := d.tags[shortTag(n.Tag)]; ok {
+		return d.callTagDecoder(n, out, f)
+	}
 	out, unmarshaled, good := d.prepare(n, out)
 	if unmarshaled {
 		return good
@@ -553,11 +703,15 @@ func (d *decoder) unmarshal(n *Node, out reflect.Value) (good bool) {
 		}
 		fallthrough
 	default:
-		fail(unmarshalErrf(n, out.Type(), "cannot decode node with unknown kind %d", n.Kind))
+		d.fail(unmarshalErrf(n, out.Type(), "cannot decode node with unknown kind %d", n.Kind))
 	}
 	return good
 }
 
+// contextCheckInterval is the number of decoded nodes between checks
+// of the context cancellation.
+const contextCheckInterval = 1024
+
 func (d *decoder) document(n *Node, out reflect.Value) (good bool) {
 	if len(n.Content) == 1 {
 		d.doc = n
@@ -574,6 +728,30 @@ func (d *decoder) alias(n *Node, out reflect.Value) (good bool) {
 	return good
 }
 
+// expandEnv returns a copy of the scalar node with environment variable
+// references expanded.
+//
+// Only plain and double-quoted scalars without explicit tag are expanded.
+// The tag of a plain scalar is resolved again.
+func (d *decoder) expandEnv(n *Node, out reflect.Value) *Node {
+	if n.Style&(TaggedStyle|SingleQuotedStyle|LiteralStyle|FoldedStyle) != 0 {
+		return n
+	}
+	value, err := expandEnv(n.Value, d.lookupEnv)
+	if err != nil {
+		d.fail(&UnmarshalError{Node: n, Type: out.Type(), Err: err})
+	}
+	if value == n.Value {
+		return n
+	}
+	c := *n
+	c.Value = value
+	if c.Style&DoubleQuotedStyle == 0 {
+		c.Tag, _ = resolve("", value)
+	}
+	return &c
+}
+
 func (d *decoder) null(out reflect.Value) bool {
 	if out.CanAddr() {
 		switch out.Kind() {
@@ -596,7 +774,7 @@ func (d *decoder) scalar(n *Node, out reflect.Value) bool {
 		if tag == binaryTag {
 			data, err := base64.StdEncoding.DecodeString(resolved.(string))
 			if err != nil {
-				fail(unmarshalErrf(n, out.Type(), "decode !!binary: %w", err))
+				d.fail(unmarshalErrf(n, out.Type(), "decode !!binary: %w", err))
 			}
 			resolved = string(data)
 		}
@@ -758,7 +936,7 @@ func (d *decoder) sequence(n *Node, out reflect.Value) (good bool) {
 		out.Set(reflect.MakeSlice(out.Type(), l, l))
 	case reflect.Array:
 		if l != out.Len() {
-			fail(unmarshalErrf(n, out.Type(), "invalid array: want %d elements but got %d", out.Len(), l))
+			d.fail(unmarshalErrf(n, out.Type(), "invalid array: want %d elements but got %d", out.Len(), l))
 		}
 	case reflect.Interface:
 		// No type hints. Will have to use a generic sequence.
@@ -771,13 +949,16 @@ func (d *decoder) sequence(n *Node, out reflect.Value) (good bool) {
 	et := out.Type().Elem()
 
 	j := 0
+	depth := len(d.path)
 	for i := 0; i < l; i++ {
+		d.path = append(d.path[:depth], PathElem{Index: i, IsIndex: true})
 		e := reflect.New(et).Elem()
 		if ok := d.unmarshal(n.Content[i], e); ok {
 			out.Index(j).Set(e)
 			j++
 		}
 	}
+	d.path = d.path[:depth]
 	if out.Kind() != reflect.Array {
 		out.Set(out.Slice(0, j))
 	}
@@ -787,30 +968,72 @@ func (d *decoder) sequence(n *Node, out reflect.Value) (good bool) {
 	return true
 }
 
-func failWantHashable(n *Node, val reflect.Value) {
-	fail(unmarshalErrf(n, val.Type(), "invalid map key: %#v", val.Interface()))
+func (d *decoder) failWantHashable(n *Node, val reflect.Value) {
+	d.fail(unmarshalErrf(n, val.Type(), "invalid map key: %#v", val.Interface()))
 }
 
-func (d *decoder) mapping(n *Node, out reflect.Value) (good bool) {
+// checkDuplicateKeys checks mapping n for repeated keys and applies the
+// configured policy.
+//
+// It returns the set of key-value pairs (indexed by key index / 2)
+// that must be skipped, and false if the mapping must not be decoded.
+func (d *decoder) checkDuplicateKeys(n *Node, out reflect.Value) (skip []bool, ok bool) {
 	l := len(n.Content)
-	if d.uniqueKeys {
-		nerrs := len(d.terrors)
-		for i := 0; i < l; i += 2 {
-			ni := n.Content[i]
-			for j := i + 2; j < l; j += 2 {
-				nj := n.Content[j]
-				if ni.equalKey(nj) {
-					d.terrors = append(d.terrors, duplicateKeyErr(nj, ni, out.Type()))
-				}
+	if l <= 2 {
+		return nil, true
+	}
+	nerrs := len(d.terrors)
+
+	// Group keys by canonical value to compare only keys which may be equal.
+	keys := make([]string, l/2)
+	buckets := make(map[string][]int, l/2)
+	for i := 0; i < l; i += 2 {
+		key := canonicalKey(n.Content[i])
+		keys[i/2] = key
+		buckets[key] = append(buckets[key], i)
+	}
+	for i := 0; i < l; i += 2 {
+		ni := n.Content[i]
+		for _, j := range buckets[keys[i/2]] {
+			if j <= i {
+				continue
+			}
+			nj := n.Content[j]
+			if !ni.equalKey(nj) {
+				continue
+			}
+			switch d.duplicateKeys {
+			case DuplicateKeysError:
+				d.terrors = append(d.terrors, duplicateKeyErr(nj, ni, out.Type(), append(d.currentPath(), keyElem(nj))))
+				continue
+			case DuplicateKeysWarn:
+				d.warn(duplicateKeyErr(nj, ni, out.Type(), append(d.currentPath(), keyElem(nj))))
+			}
+			if skip == nil {
+				skip = make([]bool, l/2)
+			}
+			if d.duplicateKeys == DuplicateKeysFirstWins {
+				skip[j/2] = true
+			} else {
+				skip[i/2] = true
 			}
 		}
-		if len(d.terrors) > nerrs {
-			return false
-		}
+	}
+	return skip, len(d.terrors) == nerrs
+}
+
+func (d *decoder) mapping(n *Node, out reflect.Value) (good bool) {
+	l := len(n.Content)
+	skip, ok := d.checkDuplicateKeys(n, out)
+	if !ok {
+		return false
+	}
+	if out.Type() == mapSliceType || out.Type() == ifaceType && d.orderedMaps {
+		return d.mappingSlice(n, out, skip)
 	}
 	switch out.Kind() {
 	case reflect.Struct:
-		return d.mappingStruct(n, out)
+		return d.mappingStruct(n, out, skip)
 	case reflect.Map:
 		// okay
 	case reflect.Interface:
@@ -850,15 +1073,20 @@ func (d *decoder) mapping(n *Node, out reflect.Value) (good bool) {
 		out.Set(reflect.MakeMap(outt))
 		mapIsNew = true
 	}
+	depth := len(d.path)
 	for i := 0; i < l; i += 2 {
+		if skip != nil && skip[i/2] {
+			continue
+		}
 		if isMerge(n.Content[i]) {
 			mergeNode = n.Content[i+1]
 			continue
 		}
+		d.path = append(d.path[:depth], keyElem(n.Content[i]))
 		k := reflect.New(kt).Elem()
 		if d.unmarshal(n.Content[i], k) {
 			if !isHashable(k) {
-				failWantHashable(n.Content[i], k)
+				d.failWantHashable(n.Content[i], k)
 				return false
 			}
 			if mergedFields != nil {
@@ -874,6 +1102,7 @@ func (d *decoder) mapping(n *Node, out reflect.Value) (good bool) {
 			}
 		}
 	}
+	d.path = d.path[:depth]
 
 	d.mergedFields = mergedFields
 	if mergeNode != nil {
@@ -885,6 +1114,66 @@ func (d *decoder) mapping(n *Node, out reflect.Value) (good bool) {
 	return true
 }
 
+// mappingSlice decodes the mapping into a MapSlice, preserving the order
+// of keys.
+func (d *decoder) mappingSlice(n *Node, out reflect.Value, skip []bool) (good bool) {
+	if !d.orderedMaps {
+		// Keep the order of nested mappings too.
+		d.orderedMaps = true
+		defer func() {
+			d.orderedMaps = false
+		}()
+	}
+
+	var slice MapSlice
+	mergedFields := d.mergedFields
+	if mergedFields != nil {
+		// Merging into the already decoded mapping.
+		slice, _ = out.Interface().(MapSlice)
+	}
+	d.mergedFields = nil
+
+	var mergeNode *Node
+	l := len(n.Content)
+	depth := len(d.path)
+	for i := 0; i < l; i += 2 {
+		if skip != nil && skip[i/2] {
+			continue
+		}
+		if isMerge(n.Content[i]) {
+			mergeNode = n.Content[i+1]
+			continue
+		}
+		d.path = append(d.path[:depth], keyElem(n.Content[i]))
+		k := reflect.New(ifaceType).Elem()
+		if !d.unmarshal(n.Content[i], k) {
+			continue
+		}
+		if !isHashable(k) {
+			d.failWantHashable(n.Content[i], k)
+			return false
+		}
+		if mergedFields != nil {
+			ki := k.Interface()
+			if _, ok := mergedFields[ki]; ok {
+				continue
+			}
+			mergedFields[ki] = struct{}{}
+		}
+		v := reflect.New(ifaceType).Elem()
+		d.unmarshal(n.Content[i+1], v)
+		slice = append(slice, MapItem{Key: k.Interface(), Value: v.Interface()})
+	}
+	d.path = d.path[:depth]
+	out.Set(reflect.ValueOf(slice))
+
+	d.mergedFields = mergedFields
+	if mergeNode != nil {
+		d.merge(n, mergeNode, out)
+	}
+	return true
+}
+
 func isStringMap(n *Node) bool {
 	if n.Kind != MappingNode {
 		return false
@@ -899,8 +1188,8 @@ func isStringMap(n *Node) bool {
 	return true
 }
 
-func (d *decoder) mappingStruct(n *Node, out reflect.Value) (good bool) {
-	sinfo, err := getStructInfo(out.Type())
+func (d *decoder) mappingStruct(n *Node, out reflect.Value, skip []bool) (good bool) {
+	sinfo, err := getStructInfo(out.Type(), d.jsonTags)
 	if err != nil {
 		panic(err)
 	}
@@ -920,38 +1209,59 @@ func (d *decoder) mappingStruct(n *Node, out reflect.Value) (good bool) {
 	mergedFields := d.mergedFields
 	d.mergedFields = nil
 	var mergeNode *Node
-	var doneFields []bool
-	if d.uniqueKeys {
-		doneFields = make([]bool, len(sinfo.FieldsList))
+	// doneFields holds keys of set fields to detect different keys of
+	// the same field, like aliases or keys differing in case.
+	doneFields := make([]*Node, len(sinfo.FieldsList))
+	var seenFields []bool
+	if (sinfo.Required || sinfo.Defaults) && mergedFields == nil {
+		seenFields = make([]bool, len(sinfo.FieldsList))
 	}
 	name := settableValueOf("")
 	l := len(n.Content)
+	depth := len(d.path)
 	for i := 0; i < l; i += 2 {
 		ni := n.Content[i]
+		if skip != nil && skip[i/2] {
+			continue
+		}
 		if isMerge(ni) {
 			mergeNode = n.Content[i+1]
 			continue
 		}
+		d.path = append(d.path[:depth], keyElem(ni))
 		if !d.unmarshal(ni, name) {
 			continue
 		}
 		sname := name.String()
+		info, ok := sinfo.field(sname, d.caseInsensitive)
 		if mergedFields != nil {
-			if _, ok := mergedFields[sname]; ok {
+			key := sname
+			if ok {
+				key = info.Key
+			}
+			if _, merged := mergedFields[key]; merged {
 				continue
 			}
-			mergedFields[sname] = struct{}{}
+			mergedFields[key] = struct{}{}
 		}
 
-		switch info, ok := sinfo.FieldsMap[sname]; {
+		switch {
 		case ok:
-			if d.uniqueKeys {
-				if doneFields[info.ID] {
-					// TODO(tdakkota): find second occurrence?
-					d.terrors = append(d.terrors, duplicateKeyErr(ni, nil, out.Type()))
+			prev := doneFields[info.ID]
+			if prev != nil {
+				switch d.duplicateKeys {
+				case DuplicateKeysError:
+					d.terrors = append(d.terrors, duplicateKeyErr(ni, prev, out.Type(), d.currentPath()))
+					continue
+				case DuplicateKeysFirstWins:
 					continue
+				case DuplicateKeysWarn:
+					d.warn(duplicateKeyErr(ni, prev, out.Type(), d.currentPath()))
 				}
-				doneFields[info.ID] = true
+			}
+			doneFields[info.ID] = ni
+			if seenFields != nil {
+				seenFields[info.ID] = true
 			}
 			var field reflect.Value
 			if info.Inline == nil {
@@ -959,7 +1269,15 @@ func (d *decoder) mappingStruct(n *Node, out reflect.Value) (good bool) {
 			} else {
 				field = d.fieldByIndex(n, out, info.Inline)
 			}
-			d.unmarshal(n.Content[i+1], field)
+			if prev != nil {
+				// The last value wins, do not merge it into the previous one.
+				field.Set(reflect.Zero(field.Type()))
+			}
+			value := n.Content[i+1]
+			if info.String {
+				value = unquoteScalar(value)
+			}
+			d.unmarshal(value, field)
 		case sinfo.InlineMap != -1:
 			if inlineMap.IsNil() {
 				inlineMap.Set(reflect.MakeMap(inlineMap.Type()))
@@ -968,33 +1286,126 @@ func (d *decoder) mappingStruct(n *Node, out reflect.Value) (good bool) {
 			d.unmarshal(n.Content[i+1], value)
 			inlineMap.SetMapIndex(name, value)
 		case d.knownFields:
-			d.terrors = append(d.terrors, unknownFieldErr(name.String(), ni, out.Type()))
+			d.terrors = append(d.terrors, unknownFieldErr(name.String(), ni, out.Type(), d.currentPath()))
 		}
 	}
+	d.path = d.path[:depth]
 
 	d.mergedFields = mergedFields
+	if seenFields != nil && sinfo.Defaults {
+		// Apply defaults before merging, so merged values win.
+		d.defaults(n, out, sinfo, seenFields)
+	}
+	var merged map[any]struct{}
 	if mergeNode != nil {
-		d.merge(n, mergeNode, out)
+		merged = d.merge(n, mergeNode, out)
+	}
+	if seenFields != nil {
+		for _, info := range sinfo.FieldsList {
+			if !info.Required || seenFields[info.ID] {
+				continue
+			}
+			if _, ok := merged[info.Key]; ok {
+				continue
+			}
+			path := append(d.currentPath(), PathElem{Key: info.Key})
+			d.terrors = append(d.terrors, missingFieldErr(info.Key, n, out.Type(), path))
+		}
 	}
 	return true
 }
 
-func failWantMap(merge *Node, typ reflect.Type) {
-	fail(unmarshalErrf(merge, typ, "map merge requires map or sequence of maps as the value"))
+// defaults sets default values of struct fields not found in the mapping.
+//
+// Nested structs not found in the mapping get their defaults too.
+func (d *decoder) defaults(n *Node, out reflect.Value, sinfo *structInfo, seen []bool) {
+	depth := len(d.path)
+	defer func() {
+		d.path = d.path[:depth]
+	}()
+	for _, info := range sinfo.FieldsList {
+		if seen != nil && seen[info.ID] {
+			continue
+		}
+		index := info.Inline
+		if index == nil {
+			index = []int{info.Num}
+		}
+		var nested *structInfo
+		if info.Default == "" {
+			nested = nestedDefaults(out.Type().FieldByIndex(index).Type, d.jsonTags)
+			if nested == nil {
+				continue
+			}
+		}
+
+		var field reflect.Value
+		if info.Inline == nil {
+			field = out.Field(info.Num)
+		} else {
+			field = d.fieldByIndex(n, out, info.Inline)
+		}
+		d.path = append(d.path[:depth], PathElem{Key: info.Key})
+		if nested != nil {
+			d.defaults(n, field, nested, nil)
+			continue
+		}
+		value := &Node{
+			Kind:   ScalarNode,
+			Value:  info.Default,
+			Line:   n.Line,
+			Column: n.Column,
+			File:   n.File,
+		}
+		value.Tag, _ = resolve("", value.Value)
+		d.unmarshal(value, field)
+	}
 }
 
-func (d *decoder) merge(parent, merge *Node, out reflect.Value) {
+// unquoteScalar returns a copy of the quoted scalar node with
+// the tag resolved as if it was plain.
+func unquoteScalar(n *Node) *Node {
+	if n.Kind != ScalarNode || n.Style&TaggedStyle != 0 ||
+		n.Style&(DoubleQuotedStyle|SingleQuotedStyle) == 0 {
+		return n
+	}
+	c := *n
+	c.Style &^= DoubleQuotedStyle | SingleQuotedStyle
+	c.Tag, _ = resolve("", c.Value)
+	return &c
+}
+
+func (d *decoder) failWantMap(merge *Node, typ reflect.Type) {
+	d.fail(unmarshalErrf(merge, typ, "map merge requires map or sequence of maps as the value"))
+}
+
+// merge merges given mapping or sequence of mappings into out.
+//
+// It returns the set of keys found in parent and merged mappings.
+func (d *decoder) merge(parent, merge *Node, out reflect.Value) (merged map[any]struct{}) {
 	mergedFields := d.mergedFields
 	if mergedFields == nil {
+		var sinfo *structInfo
+		if out.Kind() == reflect.Struct {
+			sinfo, _ = getStructInfo(out.Type(), d.jsonTags)
+		}
 		d.mergedFields = make(map[any]struct{})
 		for i := 0; i < len(parent.Content); i += 2 {
 			k := reflect.New(ifaceType).Elem()
 			if n := parent.Content[i]; d.unmarshal(n, k) {
 				if !isHashable(k) {
-					failWantHashable(n, k)
+					d.failWantHashable(n, k)
 					return
 				}
-				d.mergedFields[k.Interface()] = struct{}{}
+				key := k.Interface()
+				if s, ok := key.(string); ok && sinfo != nil {
+					// Struct fields are tracked by their key, so that
+					// aliases can't override the field.
+					if info, ok := sinfo.field(s, d.caseInsensitive); ok {
+						key = info.Key
+					}
+				}
+				d.mergedFields[key] = struct{}{}
 			}
 		}
 	}
@@ -1004,7 +1415,7 @@ func (d *decoder) merge(parent, merge *Node, out reflect.Value) {
 		d.unmarshal(merge, out)
 	case AliasNode:
 		if a := merge.Alias; a != nil && a.Kind != MappingNode {
-			failWantMap(a, out.Type())
+			d.failWantMap(a, out.Type())
 		}
 		d.unmarshal(merge, out)
 	case SequenceNode:
@@ -1012,18 +1423,20 @@ func (d *decoder) merge(parent, merge *Node, out reflect.Value) {
 			ni := merge.Content[i]
 			if ni.Kind == AliasNode {
 				if a := ni.Alias; a != nil && a.Kind != MappingNode {
-					failWantMap(a, out.Type())
+					d.failWantMap(a, out.Type())
 				}
 			} else if ni.Kind != MappingNode {
-				failWantMap(ni, out.Type())
+				d.failWantMap(ni, out.Type())
 			}
 			d.unmarshal(ni, out)
 		}
 	default:
-		failWantMap(merge, out.Type())
+		d.failWantMap(merge, out.Type())
 	}
 
+	merged = d.mergedFields
 	d.mergedFields = mergedFields
+	return merged
 }
 
 func isMerge(n *Node) bool {
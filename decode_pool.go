@@ -37,4 +37,7 @@ func (p *parser) reset() {
 	p.anchors = nil
 	p.doneInit = false
 	p.textless = false
+	p.maxScalarLength = 0
+	p.maxNodes = 0
+	p.nodes = 0
 }
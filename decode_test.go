@@ -17,10 +17,12 @@ package yaml_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
@@ -28,6 +30,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 
 	"github.com/go-faster/errors"
 
@@ -1263,6 +1266,110 @@ func TestDecoder(t *testing.T) {
 	}
 }
 
+func TestUnmarshalAll(t *testing.T) {
+	for i, item := range decoderTests {
+		item := item
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			t.Logf("Input: %q", item.data)
+			a := require.New(t)
+
+			values := []any{"garbage"}
+			a.NoError(yaml.UnmarshalAll([]byte(item.data), &values))
+			if item.values == nil {
+				a.Empty(values)
+				return
+			}
+			a.Equal(item.values, values)
+		})
+	}
+}
+
+func TestUnmarshalAllTyped(t *testing.T) {
+	a := require.New(t)
+
+	type manifest struct {
+		Kind string
+		Spec struct {
+			Replicas int
+		}
+	}
+	input := `
+kind: Deployment
+spec:
+  replicas: 3
+---
+kind: Service
+---
+kind: Job
+spec:
+  replicas: foo
+`
+	var manifests []manifest
+	err := yaml.UnmarshalAll([]byte(input), &manifests)
+	var typeErr *yaml.TypeError
+	a.ErrorAs(err, &typeErr)
+	a.Regexp("line 10: cannot unmarshal !!str `foo` into int", err.Error())
+
+	a.Len(manifests, 3)
+	a.Equal("Deployment", manifests[0].Kind)
+	a.Equal(3, manifests[0].Spec.Replicas)
+	a.Equal("Service", manifests[1].Kind)
+	a.Equal("Job", manifests[2].Kind)
+}
+
+func TestUnmarshalAllErrors(t *testing.T) {
+	a := require.New(t)
+
+	var v []any
+	a.Error(yaml.UnmarshalAll([]byte("a: 1"), v))
+	a.Error(yaml.UnmarshalAll([]byte("a: 1"), (*[]any)(nil)))
+	a.Error(yaml.UnmarshalAll([]byte("a: 1"), &struct{}{}))
+
+	err := yaml.UnmarshalAll([]byte("a: 1\n---\na: [\n"), &v)
+	var syntaxErr *yaml.SyntaxError
+	a.ErrorAs(err, &syntaxErr)
+}
+
+func TestDocuments(t *testing.T) {
+	a := require.New(t)
+
+	input := "a: 1\n---\nb: 2\n---\n- c\n"
+	var (
+		docs  = yaml.Documents([]byte(input))
+		lines []int
+	)
+	for docs.Next() {
+		a.Equal(len(lines), docs.Index())
+		n := docs.Node()
+		a.Equal(yaml.DocumentNode, n.Kind)
+		a.Len(n.Content, 1)
+		lines = append(lines, n.Content[0].Line)
+	}
+	a.NoError(docs.Err())
+	a.Equal([]int{1, 3, 5}, lines)
+	a.False(docs.Next())
+	a.Nil(docs.Node())
+
+	docs = yaml.Documents([]byte("a: 1\n---\na: [\n"))
+	a.True(docs.Next())
+	a.False(docs.Next())
+	a.Error(docs.Err())
+	a.Equal(0, docs.Index())
+
+	docs = yaml.Documents(nil)
+	a.False(docs.Next())
+	a.NoError(docs.Err())
+
+	// Stop early.
+	docs = yaml.Documents([]byte(input))
+	a.True(docs.Next())
+	docs.Close()
+	a.Nil(docs.Node())
+	a.False(docs.Next())
+	a.NoError(docs.Err())
+	docs.Close()
+}
+
 func TestDecoderReadError(t *testing.T) {
 	testError := errors.New("some read error")
 	err := yaml.NewDecoder(iotest.ErrReader(testError)).Decode(&struct{}{})
@@ -1469,8 +1576,8 @@ var unmarshalErrorTests = []struct {
 	{"- >\n \t\n\tdetected\n", "yaml: line 3: found a tab character where an indentation space is expected"},
 
 	// https://github.com/go-faster/yaml/issues/20
-	{"0:\n00:\n 000\n<<:\n  {}:", `yaml: line 5: invalid map key: map\[string\]interface \{\}\{\}`},
-	{"0:\n00:\n 000\n<<:\n  []:", `yaml: line 5: invalid map key: \[\]interface \{\}\{\}`},
+	{"0:\n1:\n 000\n<<:\n  {}:", `yaml: line 5: invalid map key: map\[string\]interface \{\}\{\}`},
+	{"0:\n1:\n 000\n<<:\n  []:", `yaml: line 5: invalid map key: \[\]interface \{\}\{\}`},
 	{"{}:", `yaml: line 1: invalid map key: map\[string\]interface \{\}\{\}`},
 	{"[]:", `yaml: line 1: invalid map key: \[\]interface \{\}\{\}`},
 
@@ -2224,6 +2331,1020 @@ func TestUnmarshalKnownFields(t *testing.T) {
 	}
 }
 
+var duplicateKeysTests = []struct {
+	policy   yaml.DuplicateKeyPolicy
+	data     string
+	value    any
+	warnings []string
+}{
+	{
+		policy: yaml.DuplicateKeysLastWins,
+		data:   "a: 1\nb: 2\na: 3\n",
+		value:  struct{ A, B int }{A: 3, B: 2},
+	},
+	{
+		policy: yaml.DuplicateKeysFirstWins,
+		data:   "a: 1\nb: 2\na: 3\n",
+		value:  struct{ A, B int }{A: 1, B: 2},
+	},
+	{
+		policy: yaml.DuplicateKeysLastWins,
+		data:   "a: {x: 1}\na: {y: 2}\n",
+		value:  map[string]map[string]int{"a": {"y": 2}},
+	},
+	{
+		policy: yaml.DuplicateKeysFirstWins,
+		data:   "a: {x: 1}\na: {y: 2}\n",
+		value:  map[string]map[string]int{"a": {"x": 1}},
+	},
+	{
+		policy: yaml.DuplicateKeysLastWins,
+		data:   "a: 1\n9: 2\nnull: 3\n9: 4",
+		value:  map[any]any{"a": 1, 9: 4, nil: 3},
+	},
+	{
+		policy: yaml.DuplicateKeysFirstWins,
+		data:   "a: 1\n9: 2\nnull: 3\n9: 4",
+		value:  map[any]any{"a": 1, 9: 2, nil: 3},
+	},
+	{
+		policy:   yaml.DuplicateKeysWarn,
+		data:     "a: 1\nb: 2\na: 3\n",
+		value:    struct{ A, B int }{A: 3, B: 2},
+		warnings: []string{`yaml: line 3: mapping key "a" already defined at line 1`},
+	},
+	{
+		policy: yaml.DuplicateKeysWarn,
+		data:   "a: 1\na: 2\na: 3\n",
+		value:  map[string]int{"a": 3},
+		warnings: []string{
+			`yaml: line 2: mapping key "a" already defined at line 1`,
+			`yaml: line 3: mapping key "a" already defined at line 1`,
+			`yaml: line 3: mapping key "a" already defined at line 2`,
+		},
+	},
+	// Keys are compared by canonical values.
+	{
+		policy: yaml.DuplicateKeysLastWins,
+		data:   "0x10: a\n16: b\n",
+		value:  map[int]string{16: "b"},
+	},
+	{
+		policy:   yaml.DuplicateKeysWarn,
+		data:     "1: a\n'1': b\n0x1: c\n",
+		value:    map[any]any{1: "c", "1": "b"},
+		warnings: []string{`yaml: line 3: mapping key "1" already defined at line 1`},
+	},
+	{
+		policy: yaml.DuplicateKeysError,
+		data:   "1: a\n1.0: b\n",
+		value:  map[any]any{1: "a", 1.0: "b"},
+	},
+	{
+		policy: yaml.DuplicateKeysError,
+		data:   "10: a\n1e1: b\n",
+		value:  map[string]string{"10": "a", "1e1": "b"},
+	},
+	// Merge keys.
+	{
+		policy: yaml.DuplicateKeysLastWins,
+		data:   "base: &base {a: 1, a: 2, b: 3}\nv:\n  <<: *base\n  b: 4\n",
+		value: map[string]struct{ A, B int }{
+			"base": {A: 2, B: 3},
+			"v":    {A: 2, B: 4},
+		},
+	},
+	{
+		policy: yaml.DuplicateKeysFirstWins,
+		data:   "base: &base {a: 1, a: 2, b: 3}\nv:\n  <<: *base\n  b: 4\n",
+		value: map[string]map[string]int{
+			"base": {"a": 1, "b": 3},
+			"v":    {"a": 1, "b": 4},
+		},
+	},
+	{
+		policy: yaml.DuplicateKeysFirstWins,
+		data:   "x: &x {a: 1}\ny: &y {a: 2}\nv:\n  <<: *x\n  <<: *y\n",
+		value: map[string]struct{ A int }{
+			"x": {A: 1},
+			"y": {A: 2},
+			"v": {A: 1},
+		},
+	},
+	{
+		policy: yaml.DuplicateKeysLastWins,
+		data:   "x: &x {a: 1}\ny: &y {a: 2}\nv:\n  <<: *x\n  <<: *y\n",
+		value: map[string]struct{ A int }{
+			"x": {A: 1},
+			"y": {A: 2},
+			"v": {A: 2},
+		},
+	},
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	for i, item := range duplicateKeysTests {
+		item := item
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			t.Logf("Input: %q, policy: %s", item.data, item.policy)
+
+			check := func(t *testing.T, decode func(v any, opts yaml.DecodeOptions) error) {
+				a := require.New(t)
+
+				var warnings []string
+				typ := reflect.ValueOf(item.value).Type()
+				value := reflect.New(typ)
+				err := decode(value.Interface(), yaml.DecodeOptions{
+					DuplicateKeys: item.policy,
+					OnWarning: func(err error) {
+						var uerr *yaml.UnmarshalError
+						a.ErrorAs(err, &uerr)
+						warnings = append(warnings, err.Error())
+					},
+				})
+				a.NoError(err)
+				a.Equal(item.value, value.Elem().Interface())
+				a.Equal(item.warnings, warnings)
+			}
+
+			t.Run("Unmarshal", func(t *testing.T) {
+				check(t, func(v any, opts yaml.DecodeOptions) error {
+					return yaml.UnmarshalWithOptions([]byte(item.data), v, opts)
+				})
+			})
+			t.Run("Decoder", func(t *testing.T) {
+				check(t, func(v any, opts yaml.DecodeOptions) error {
+					dec := yaml.NewDecoder(strings.NewReader(item.data))
+					dec.DuplicateKeys(opts.DuplicateKeys)
+					dec.OnWarning(opts.OnWarning)
+					return dec.Decode(v)
+				})
+			})
+			t.Run("Node", func(t *testing.T) {
+				check(t, func(v any, opts yaml.DecodeOptions) error {
+					var n yaml.Node
+					if err := yaml.Unmarshal([]byte(item.data), &n); err != nil {
+						return err
+					}
+					return n.DecodeWithOptions(v, opts)
+				})
+			})
+		})
+	}
+}
+
+func TestUnmarshalJSONTags(t *testing.T) {
+	a := require.New(t)
+
+	type Embedded struct {
+		Namespace string `json:"namespace,omitempty"`
+	}
+	type value struct {
+		*Embedded
+		APIVersion string `json:"apiVersion"`
+		Replicas   int    `json:",omitempty"`
+		Secret     string `json:"-"`
+		Name       string `json:"name" yaml:"title"`
+	}
+	input := "namespace: default\napiVersion: v1\nReplicas: 3\nsecret: foo\ntitle: bar\n"
+
+	var v value
+	a.NoError(yaml.UnmarshalWithOptions([]byte(input), &v, yaml.DecodeOptions{JSONTags: true}))
+	a.Equal(value{
+		Embedded:   &Embedded{Namespace: "default"},
+		APIVersion: "v1",
+		Replicas:   3,
+		Name:       "bar",
+	}, v)
+
+	v = value{}
+	dec := yaml.NewDecoder(strings.NewReader(input))
+	dec.UseJSONTags(true)
+	dec.KnownFields(true)
+	err := dec.Decode(&v)
+	a.Error(err)
+	a.Regexp(`line 4: field "secret" not found`, err.Error())
+	a.Equal("v1", v.APIVersion)
+}
+
+func TestDecoderRegisterTag(t *testing.T) {
+	a := require.New(t)
+
+	type config struct {
+		Password *string
+		Pattern  *regexp.Regexp
+		Ref      string
+		Refs     []string
+		Port     int
+	}
+	secrets := map[string]string{"db": "hunter2"}
+
+	input := `
+password: !secret db
+pattern: !regex '^a+$'
+ref: !ref {name: foo}
+refs: !ref [a, b]
+port: !secret unknown
+`
+	dec := yaml.NewDecoder(strings.NewReader(input))
+	dec.RegisterTag("!secret", func(n *yaml.Node, out reflect.Value) error {
+		s, ok := secrets[n.Value]
+		if !ok {
+			return &yaml.TypeError{Group: &yaml.UnmarshalError{
+				Node: n,
+				Type: out.Type(),
+				Err:  errors.Errorf("unknown secret %q", n.Value),
+			}}
+		}
+		out.SetString(s)
+		return nil
+	})
+	dec.RegisterTag("!regex", func(n *yaml.Node, out reflect.Value) error {
+		re, err := regexp.Compile(n.Value)
+		if err != nil {
+			return err
+		}
+		out.Set(reflect.ValueOf(*re))
+		return nil
+	})
+	dec.RegisterTag("!ref", func(n *yaml.Node, out reflect.Value) error {
+		switch n.Kind {
+		case yaml.MappingNode:
+			var v struct{ Name string }
+			if err := n.Decode(&v); err != nil {
+				return err
+			}
+			out.SetString(v.Name)
+		case yaml.SequenceNode:
+			var v []string
+			if err := n.Decode(&v); err != nil {
+				return err
+			}
+			out.Set(reflect.ValueOf(v))
+		}
+		return nil
+	})
+
+	var c config
+	err := dec.Decode(&c)
+	a.Error(err)
+	a.Regexp(`line 6: unknown secret "unknown"`, err.Error())
+
+	a.NotNil(c.Password)
+	a.Equal("hunter2", *c.Password)
+	a.NotNil(c.Pattern)
+	a.True(c.Pattern.MatchString("aaa"))
+	a.Equal("foo", c.Ref)
+	a.Equal([]string{"a", "b"}, c.Refs)
+
+	// Non-TypeError errors stop decoding.
+	err = yaml.UnmarshalWithOptions([]byte("a: !regex '['"), &map[string]regexp.Regexp{}, yaml.DecodeOptions{
+		Tags: map[string]yaml.TagDecodeFunc{
+			"!regex": func(n *yaml.Node, out reflect.Value) error {
+				_, err := regexp.Compile(n.Value)
+				return err
+			},
+		},
+	})
+	a.Error(err)
+
+	// Tags are passed through without registered decoders.
+	var v map[string]any
+	a.NoError(yaml.Unmarshal([]byte(input), &v))
+	a.Equal("db", v["password"])
+	a.Equal(map[string]any{"name": "foo"}, v["ref"])
+
+	// Interface targets get a value of the default type.
+	dec = yaml.NewDecoder(strings.NewReader("!secret db"))
+	dec.RegisterTag("!secret", func(n *yaml.Node, out reflect.Value) error {
+		out.SetString(secrets[n.Value])
+		return nil
+	})
+	var secret any
+	a.NoError(dec.Decode(&secret))
+	a.Equal("hunter2", secret)
+
+	dec = yaml.NewDecoder(strings.NewReader("- !ref {name: foo}\n- !ref [a, b]\n"))
+	dec.RegisterTag("!ref", func(n *yaml.Node, out reflect.Value) error {
+		return n.Decode(out.Addr().Interface())
+	})
+	var refs any
+	a.NoError(dec.Decode(&refs))
+	a.Equal([]any{
+		map[string]any{"name": "foo"},
+		[]any{"a", "b"},
+	}, refs)
+}
+
+func TestDecoderExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"HOST":  "localhost",
+		"PORT":  "8080",
+		"DEBUG": "true",
+		"EMPTY": "",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		data   string
+		value  any
+		expect any
+		err    string
+	}{
+		{"v: ${HOST}", &map[string]string{}, &map[string]string{"v": "localhost"}, ""},
+		{"v: ${PORT}", &map[string]int{}, &map[string]int{"v": 8080}, ""},
+		{"v: ${PORT}", &map[string]any{}, &map[string]any{"v": 8080}, ""},
+		{"v: ${DEBUG}", &map[string]bool{}, &map[string]bool{"v": true}, ""},
+		{`v: "${PORT}"`, &map[string]any{}, &map[string]any{"v": "8080"}, ""},
+		{"v: '${PORT}'", &map[string]any{}, &map[string]any{"v": "${PORT}"}, ""},
+		{"v: !!str ${PORT}", &map[string]any{}, &map[string]any{"v": "${PORT}"}, ""},
+		{"v: |\n  ${PORT}\n", &map[string]any{}, &map[string]any{"v": "${PORT}\n"}, ""},
+		{"v: http://${HOST}:${PORT}/", &map[string]string{}, &map[string]string{"v": "http://localhost:8080/"}, ""},
+		{"v: $${HOST}", &map[string]string{}, &map[string]string{"v": "${HOST}"}, ""},
+		{"v: ${MISSING}", &map[string]any{}, &map[string]any{"v": nil}, ""},
+		{"v: ${MISSING:-80}", &map[string]int{}, &map[string]int{"v": 80}, ""},
+		{"v: ${EMPTY:-x}", &map[string]string{}, &map[string]string{"v": "x"}, ""},
+		{"v: ${HOST:?host is required}", &map[string]string{}, &map[string]string{"v": "localhost"}, ""},
+		{"${HOST}: 1", &map[string]int{}, &map[string]int{"localhost": 1}, ""},
+		{
+			"a: 1\nv: ${MISSING:?set MISSING}", &map[string]any{}, nil,
+			`yaml: line 2: required variable "MISSING" is not set: set MISSING`,
+		},
+		{"v: ${EMPTY:?}", &map[string]any{}, nil, `yaml: line 1: required variable "EMPTY" is not set`},
+		{"v: ${HOST", &map[string]any{}, nil, `yaml: line 1: unterminated variable reference`},
+		{"v: ${1X}", &map[string]any{}, nil, `yaml: line 1: invalid variable name "1X"`},
+		{"v: ${X:+y}", &map[string]any{}, nil, `yaml: line 1: invalid variable reference "X:+y"`},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			dec := yaml.NewDecoder(strings.NewReader(tt.data))
+			dec.ExpandEnv(true)
+			dec.LookupEnv(lookup)
+			err := dec.Decode(tt.value)
+			if tt.err != "" {
+				a.EqualError(err, tt.err)
+				var uerr *yaml.UnmarshalError
+				a.ErrorAs(err, &uerr)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tt.expect, tt.value)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		a := require.New(t)
+
+		var v map[string]string
+		a.NoError(yaml.UnmarshalWithOptions([]byte("v: ${HOST}"), &v, yaml.DecodeOptions{}))
+		a.Equal("${HOST}", v["v"])
+	})
+	t.Run("OS", func(t *testing.T) {
+		a := require.New(t)
+
+		t.Setenv("YAML_TEST_PORT", "9090")
+		var v struct {
+			Port int `yaml:"port"`
+		}
+		a.NoError(yaml.UnmarshalWithOptions([]byte("port: ${YAML_TEST_PORT}"), &v, yaml.DecodeOptions{
+			ExpandEnv: true,
+		}))
+		a.Equal(9090, v.Port)
+	})
+}
+
+func TestDecoderCaseInsensitive(t *testing.T) {
+	type Spec struct {
+		Replicas int `yaml:"replicas"`
+	}
+	type T struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		KIND       string `yaml:"KIND"`
+		Spec       Spec   `yaml:"spec"`
+	}
+
+	tests := []struct {
+		data   string
+		expect T
+		err    string
+	}{
+		{"apiVersion: v1", T{APIVersion: "v1"}, ""},
+		{"apiversion: v1", T{APIVersion: "v1"}, ""},
+		{"APIVERSION: v1\nSpec: {REPLICAS: 2}", T{APIVersion: "v1", Spec: Spec{Replicas: 2}}, ""},
+		// Exact match is preferred.
+		{"kind: a\nKIND: b", T{Kind: "a", KIND: "b"}, ""},
+		{"Kind: a", T{Kind: "a"}, ""},
+		{
+			"apiVersion: v1\napiversion: v2", T{APIVersion: "v1"},
+			"yaml: unmarshal errors:\n  yaml: line 2: mapping key \"apiVersion\" already defined at line 1",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			dec := yaml.NewDecoder(strings.NewReader(tt.data))
+			dec.CaseInsensitive(true)
+			dec.KnownFields(true)
+			var v T
+			err := dec.Decode(&v)
+			if tt.err != "" {
+				a.EqualError(err, tt.err)
+			} else {
+				a.NoError(err)
+			}
+			a.Equal(tt.expect, v)
+		})
+	}
+
+	var v T
+	a := require.New(t)
+	a.NoError(yaml.Unmarshal([]byte("apiversion: v1"), &v))
+	a.Empty(v.APIVersion)
+}
+
+func TestUnmarshalFieldAlias(t *testing.T) {
+	type T struct {
+		Name  string `yaml:"name,alias=old_name,alias=legacyName"`
+		Value int    `yaml:"value,omitempty"`
+	}
+	type Inline struct {
+		T     `yaml:",inline"`
+		Other string `yaml:"other,alias=another"`
+	}
+
+	tests := []struct {
+		data   string
+		value  any
+		expect any
+		err    string
+	}{
+		{"name: a", &T{}, &T{Name: "a"}, ""},
+		{"old_name: a", &T{}, &T{Name: "a"}, ""},
+		{"legacyName: a\nvalue: 1", &T{}, &T{Name: "a", Value: 1}, ""},
+		{"old_name: a\nanother: b", &Inline{}, &Inline{T: T{Name: "a"}, Other: "b"}, ""},
+		{"base: &b {old_name: a}\nv:\n  <<: *b\n  name: b", &map[string]T{}, &map[string]T{"base": {Name: "a"}, "v": {Name: "b"}}, ""},
+		{"base: &b {name: a}\nv:\n  <<: *b\n  legacyName: b", &map[string]T{}, &map[string]T{"base": {Name: "a"}, "v": {Name: "b"}}, ""},
+		{
+			"name: a\nold_name: b", &T{}, &T{Name: "a"},
+			"yaml: unmarshal errors:\n  yaml: line 2: mapping key \"name\" already defined at line 1",
+		},
+		{
+			"legacyName: a\nold_name: b", &T{}, &T{Name: "a"},
+			"yaml: unmarshal errors:\n  yaml: line 2: mapping key \"legacyName\" already defined at line 1",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			err := yaml.Unmarshal([]byte(tt.data), tt.value)
+			if tt.err != "" {
+				a.EqualError(err, tt.err)
+				var derr *yaml.DuplicateKeyError
+				a.ErrorAs(err, &derr)
+			} else {
+				a.NoError(err)
+			}
+			a.Equal(tt.expect, tt.value)
+		})
+	}
+
+	t.Run("DuplicateKeys", func(t *testing.T) {
+		type Spec struct {
+			Replicas int    `yaml:"replicas"`
+			Image    string `yaml:"image"`
+		}
+		type S struct {
+			Name string `yaml:"name,alias=old"`
+			Spec Spec   `yaml:"spec,alias=legacy"`
+		}
+		const input = "name: new\nold: legacy\nspec: {replicas: 1}\nlegacy: {image: app}\n"
+
+		tests := []struct {
+			policy   yaml.DuplicateKeyPolicy
+			expect   S
+			warnings []string
+		}{
+			{yaml.DuplicateKeysFirstWins, S{Name: "new", Spec: Spec{Replicas: 1}}, nil},
+			{yaml.DuplicateKeysLastWins, S{Name: "legacy", Spec: Spec{Image: "app"}}, nil},
+			{
+				yaml.DuplicateKeysWarn, S{Name: "legacy", Spec: Spec{Image: "app"}},
+				[]string{
+					`yaml: line 2: mapping key "name" already defined at line 1`,
+					`yaml: line 4: mapping key "spec" already defined at line 3`,
+				},
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.policy.String(), func(t *testing.T) {
+				a := require.New(t)
+
+				var warnings []string
+				var v S
+				a.NoError(yaml.UnmarshalWithOptions([]byte(input), &v, yaml.DecodeOptions{
+					DuplicateKeys: tt.policy,
+					OnWarning: func(err error) {
+						warnings = append(warnings, err.Error())
+					},
+				}))
+				a.Equal(tt.expect, v)
+				a.Equal(tt.warnings, warnings)
+			})
+		}
+
+		t.Run("CaseInsensitive", func(t *testing.T) {
+			a := require.New(t)
+
+			var v S
+			a.NoError(yaml.UnmarshalWithOptions([]byte("name: a\nNAME: b\n"), &v, yaml.DecodeOptions{
+				CaseInsensitive: true,
+				DuplicateKeys:   yaml.DuplicateKeysFirstWins,
+			}))
+			a.Equal("a", v.Name)
+		})
+	})
+	t.Run("Marshal", func(t *testing.T) {
+		a := require.New(t)
+
+		data, err := yaml.Marshal(T{Name: "a"})
+		a.NoError(err)
+		a.Equal("name: a\n", string(data))
+	})
+	t.Run("Conflict", func(t *testing.T) {
+		a := require.New(t)
+
+		var v struct {
+			A string `yaml:"a,alias=b"`
+			B string `yaml:"b"`
+		}
+		a.Panics(func() {
+			_ = yaml.Unmarshal([]byte("a: 1"), &v)
+		})
+		var e struct {
+			A string `yaml:"a,alias="`
+		}
+		a.Panics(func() {
+			_ = yaml.Unmarshal([]byte("a: 1"), &e)
+		})
+	})
+}
+
+func TestUnmarshalRequired(t *testing.T) {
+	type Port struct {
+		Name string `yaml:"name"`
+		Port int    `yaml:"port,required"`
+	}
+	type Meta struct {
+		Namespace string `yaml:"namespace,required"`
+	}
+	type T struct {
+		Meta  `yaml:",inline"`
+		Name  string `yaml:"name,required,alias=title"`
+		Ports []Port `yaml:"ports"`
+	}
+
+	tests := []struct {
+		data  string
+		value any
+		err   string
+	}{
+		{"name: a\nnamespace: b", &T{}, ""},
+		{"title: a\nnamespace: b\nports: [{port: 80}]", &T{}, ""},
+		{"base: &b {name: a, namespace: b}\nv:\n  <<: *b", &map[string]T{}, ""},
+		{"base: &b {port: 80}\nv:\n  <<: *b\n  name: http", &map[string]Port{}, ""},
+		{
+			"namespace: b", &T{},
+			"yaml: unmarshal errors:\n" +
+				"  yaml: line 1: required field \"name\" is missing in type yaml_test.T",
+		},
+		{
+			"base: &b {name: a}\nv:\n  <<: *b\n  title: b", &map[string]T{},
+			"yaml: unmarshal errors:\n" +
+				"  yaml: line 1: required field \"namespace\" is missing in type yaml_test.T; " +
+				"yaml: line 3: required field \"namespace\" is missing in type yaml_test.T",
+		},
+		{
+			"name: a\nports:\n- port: 80\n- name: http\n", &T{},
+			"yaml: unmarshal errors:\n" +
+				"  yaml: line 4: required field \"port\" is missing in type yaml_test.Port; " +
+				"yaml: line 1: required field \"namespace\" is missing in type yaml_test.T",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			err := yaml.Unmarshal([]byte(tt.data), tt.value)
+			if tt.err == "" {
+				a.NoError(err)
+				return
+			}
+			a.EqualError(err, tt.err)
+
+			var typeErr *yaml.TypeError
+			a.ErrorAs(err, &typeErr)
+			var missingErr *yaml.MissingFieldError
+			a.ErrorAs(err, &missingErr)
+		})
+	}
+}
+
+func TestUnmarshalStringFlag(t *testing.T) {
+	type T struct {
+		Port    int     `yaml:"port,string"`
+		Ratio   float64 `yaml:"ratio,string"`
+		Enabled *bool   `yaml:"enabled,string"`
+		Name    string  `yaml:"name,string"`
+	}
+
+	tests := []struct {
+		data   string
+		expect T
+		err    string
+	}{
+		{`port: "80"`, T{Port: 80}, ""},
+		{`port: '0x10'`, T{Port: 16}, ""},
+		{`port: 80`, T{Port: 80}, ""},
+		{`ratio: ".inf"`, T{Ratio: math.Inf(1)}, ""},
+		{`enabled: "false"`, T{Enabled: ptrTo(false)}, ""},
+		{`name: "10"`, T{Name: "10"}, ""},
+		{`port: "abc"`, T{}, "yaml: unmarshal errors:\n  yaml: line 1: cannot unmarshal !!str `abc` into int"},
+		{`port: !!str "80"`, T{}, "yaml: unmarshal errors:\n  yaml: line 1: cannot unmarshal !!str `80` into int"},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var v T
+			err := yaml.Unmarshal([]byte(tt.data), &v)
+			if tt.err != "" {
+				a.EqualError(err, tt.err)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tt.expect, v)
+		})
+	}
+
+	// The flag is ignored for other fields.
+	a := require.New(t)
+	var v struct {
+		Port int `yaml:"port"`
+	}
+	a.Error(yaml.Unmarshal([]byte(`port: "80"`), &v))
+}
+
+func TestUnmarshalDefault(t *testing.T) {
+	type Limits struct {
+		CPU    string `yaml:"cpu,default=100m"`
+		Memory int    `yaml:"memory,default=512"`
+	}
+	type Base struct {
+		Level string `yaml:"level,default=info"`
+	}
+	type T struct {
+		*Base   `yaml:",inline"`
+		Timeout time.Duration   `yaml:"timeout,default=30s"`
+		Port    int             `yaml:"port,string,default=8080"`
+		Enabled *bool           `yaml:"enabled,default=true"`
+		Name    textUnmarshaler `yaml:"name,default=anonymous"`
+		Tags    []string        `yaml:"tags"`
+		Limits  Limits          `yaml:"limits"`
+	}
+
+	tests := []struct {
+		data   string
+		expect T
+	}{
+		{
+			"{}",
+			T{
+				Base:    &Base{Level: "info"},
+				Timeout: 30 * time.Second,
+				Port:    8080,
+				Enabled: ptrTo(true),
+				Name:    textUnmarshaler{S: "anonymous"},
+				Limits:  Limits{CPU: "100m", Memory: 512},
+			},
+		},
+		{
+			"timeout: 1m\nenabled: false\nlevel: debug\nlimits: {memory: 1024}",
+			T{
+				Base:    &Base{Level: "debug"},
+				Timeout: time.Minute,
+				Port:    8080,
+				Enabled: ptrTo(false),
+				Name:    textUnmarshaler{S: "anonymous"},
+				Limits:  Limits{CPU: "100m", Memory: 1024},
+			},
+		},
+		{
+			"port: \"80\"\nname: foo\nenabled: null",
+			T{
+				Base:    &Base{Level: "info"},
+				Timeout: 30 * time.Second,
+				Port:    80,
+				Name:    textUnmarshaler{S: "foo"},
+				Limits:  Limits{CPU: "100m", Memory: 512},
+			},
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var v T
+			a.NoError(yaml.Unmarshal([]byte(tt.data), &v))
+			a.Equal(tt.expect, v)
+		})
+	}
+
+	t.Run("Merge", func(t *testing.T) {
+		a := require.New(t)
+
+		var v map[string]Limits
+		a.NoError(yaml.Unmarshal([]byte("base: &b {memory: 64}\nv:\n  <<: *b\n  cpu: 1"), &v))
+		a.Equal(map[string]Limits{
+			"base": {CPU: "100m", Memory: 64},
+			"v":    {CPU: "1", Memory: 64},
+		}, v)
+	})
+	t.Run("Error", func(t *testing.T) {
+		a := require.New(t)
+
+		var v struct {
+			Timeout time.Duration `yaml:"timeout,default=soon"`
+		}
+		err := yaml.Unmarshal([]byte("\nname: foo"), &v)
+		a.EqualError(err, "yaml: unmarshal errors:\n  yaml: line 2: cannot unmarshal !!str `soon` into time.Duration")
+	})
+}
+
+func TestUnmarshalMapSlice(t *testing.T) {
+	a := require.New(t)
+
+	const input = "z: 1\nb:\n  items: [{d: 1, c: 2}]\n  x: null\na: 3\n"
+
+	var v yaml.MapSlice
+	a.NoError(yaml.Unmarshal([]byte(input), &v))
+	a.Equal(yaml.MapSlice{
+		{Key: "z", Value: 1},
+		{Key: "b", Value: yaml.MapSlice{
+			{Key: "items", Value: []any{yaml.MapSlice{{Key: "d", Value: 1}, {Key: "c", Value: 2}}}},
+			{Key: "x", Value: nil},
+		}},
+		{Key: "a", Value: 3},
+	}, v)
+	val, ok := v.Get("a")
+	a.True(ok)
+	a.Equal(3, val)
+	_, ok = v.Get("c")
+	a.False(ok)
+
+	var ordered any
+	a.NoError(yaml.UnmarshalWithOptions([]byte(input), &ordered, yaml.DecodeOptions{
+		OrderedMaps: true,
+	}))
+	a.Equal(yaml.MapSlice{
+		{Key: "z", Value: 1},
+		{Key: "b", Value: yaml.MapSlice{
+			{Key: "items", Value: []any{yaml.MapSlice{{Key: "d", Value: 1}, {Key: "c", Value: 2}}}},
+			{Key: "x", Value: nil},
+		}},
+		{Key: "a", Value: 3},
+	}, ordered)
+
+	data, err := yaml.Marshal(ordered)
+	a.NoError(err)
+	a.Equal("z: 1\nb:\n    items:\n        -   d: 1\n            c: 2\n    x: null\na: 3\n", string(data))
+
+	// Nested mappings of MapSlice keep their order.
+	data, err = yaml.Marshal(v)
+	a.NoError(err)
+	a.Equal("z: 1\nb:\n    items:\n        -   d: 1\n            c: 2\n    x: null\na: 3\n", string(data))
+
+	var s struct {
+		M yaml.MapSlice  `yaml:"m"`
+		N map[string]any `yaml:"n"`
+	}
+	a.NoError(yaml.Unmarshal([]byte("m: {b: {d: 1, c: 2}}\nn: {b: {d: 1, c: 2}}\n"), &s))
+	a.Equal(yaml.MapSlice{{Key: "b", Value: yaml.MapSlice{{Key: "d", Value: 1}, {Key: "c", Value: 2}}}}, s.M)
+	a.Equal(map[string]any{"b": map[string]any{"d": 1, "c": 2}}, s.N)
+
+	dec := yaml.NewDecoder(strings.NewReader("base: &b {b: 1, a: 2}\nv:\n  c: 3\n  <<: *b\n  a: 4\n"))
+	dec.UseOrderedMaps(true)
+	var merged map[string]any
+	a.NoError(dec.Decode(&merged))
+	a.Equal(yaml.MapSlice{{Key: "c", Value: 3}, {Key: "a", Value: 4}, {Key: "b", Value: 1}}, merged["v"])
+
+	err = yaml.Unmarshal([]byte("a: 1\na: 2"), &v)
+	var derr *yaml.DuplicateKeyError
+	a.ErrorAs(err, &derr)
+}
+
+type tenantKey struct{}
+
+type tenantValue struct {
+	Tenant string
+	Value  string
+}
+
+func (v *tenantValue) UnmarshalYAML(ctx context.Context, n *yaml.Node) error {
+	v.Tenant, _ = ctx.Value(tenantKey{}).(string)
+	return n.DecodeContext(ctx, &v.Value, yaml.DecodeOptions{})
+}
+
+type cancelValue struct{}
+
+func (v *cancelValue) UnmarshalYAML(ctx context.Context, n *yaml.Node) error {
+	if cancel, ok := ctx.Value(tenantKey{}).(context.CancelFunc); ok {
+		cancel()
+	}
+	return nil
+}
+
+func TestDecoderDecodeContext(t *testing.T) {
+	a := require.New(t)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	var v struct {
+		A tenantValue            `yaml:"a"`
+		B map[string]tenantValue `yaml:"b"`
+	}
+	dec := yaml.NewDecoder(strings.NewReader("a: foo\nb: {c: bar}\n"))
+	a.NoError(dec.DecodeContext(ctx, &v))
+	a.Equal(tenantValue{Tenant: "acme", Value: "foo"}, v.A)
+	a.Equal(map[string]tenantValue{"c": {Tenant: "acme", Value: "bar"}}, v.B)
+
+	// Decode uses the background context.
+	dec = yaml.NewDecoder(strings.NewReader("a: foo\n"))
+	a.NoError(dec.Decode(&v))
+	a.Equal(tenantValue{Value: "foo"}, v.A)
+
+	t.Run("Cancel", func(t *testing.T) {
+		a := require.New(t)
+
+		var sb strings.Builder
+		sb.WriteString("- first\n")
+		for i := 0; i < 10000; i++ {
+			sb.WriteString("- item\n")
+		}
+		input := sb.String()
+
+		// The first element cancels the context, decoding stops midway.
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx = context.WithValue(ctx, tenantKey{}, cancel)
+		var values []cancelValue
+		dec := yaml.NewDecoder(strings.NewReader(input))
+		a.ErrorIs(dec.DecodeContext(ctx, &values), context.Canceled)
+
+		// Already cancelled context is rejected before decoding.
+		var items []any
+		dec = yaml.NewDecoder(strings.NewReader(input))
+		a.ErrorIs(dec.DecodeContext(ctx, &items), context.Canceled)
+		a.Empty(items)
+	})
+}
+
+type portsValue []int
+
+func (v *portsValue) UnmarshalYAML(n *yaml.Node) error {
+	var ports struct {
+		List []int `yaml:"list"`
+	}
+	if err := n.Decode(&ports); err != nil {
+		return err
+	}
+	*v = ports.List
+	return nil
+}
+
+func TestUnmarshalErrorPath(t *testing.T) {
+	type Port struct {
+		ContainerPort int `yaml:"containerPort"`
+	}
+	type Container struct {
+		Name  string `yaml:"name,required"`
+		Ports []Port `yaml:"ports"`
+	}
+	type Spec struct {
+		Containers []Container           `yaml:"containers"`
+		Labels     map[string]int        `yaml:"labels"`
+		Extra      map[string][]Port     `yaml:"extra"`
+		Custom     map[string]portsValue `yaml:"custom"`
+	}
+	type T struct {
+		Spec Spec `yaml:"spec"`
+	}
+
+	tests := []struct {
+		data  string
+		paths []string
+	}{
+		{
+			"spec:\n  containers:\n  - name: a\n  - name: b\n  - name: c\n    ports:\n    - containerPort: http\n",
+			[]string{"spec.containers[2].ports[0].containerPort"},
+		},
+		{
+			"spec: {labels: {app.kubernetes.io/name: web, 'it''s': x}}",
+			[]string{"spec.labels['app.kubernetes.io/name']", `spec.labels['it\'s']`},
+		},
+		{
+			"spec: {containers: [{ports: []}], unknown: 1}",
+			[]string{"spec.containers[0].name", "spec.unknown"},
+		},
+		{
+			"spec: {containers: [{name: a, name: b}]}",
+			[]string{"spec.containers[0].name"},
+		},
+		{
+			"spec: {extra: {a: [{containerPort: 1}, {containerPort: x}], a: []}}",
+			[]string{"spec.extra.a"},
+		},
+		{
+			"base: &b [{containerPort: x}]\nspec: {extra: {<<: {a: *b}}}",
+			[]string{"spec.extra.a[0].containerPort"},
+		},
+		{
+			"spec: {custom: {a: {list: [1, x]}}}",
+			[]string{"spec.custom.a.list[1]"},
+		},
+		{
+			"spec: [1]",
+			[]string{"spec"},
+		},
+		{
+			"[1]",
+			[]string{""},
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var v struct {
+				T    `yaml:",inline"`
+				Base any `yaml:"base"`
+			}
+			dec := yaml.NewDecoder(strings.NewReader(tt.data))
+			dec.KnownFields(true)
+			err := dec.Decode(&v)
+
+			var terr *yaml.TypeError
+			a.ErrorAs(err, &terr)
+			var paths []string
+			for _, err := range multierr.Errors(terr.Group) {
+				var uerr *yaml.UnmarshalError
+				a.ErrorAs(err, &uerr)
+				paths = append(paths, uerr.Path.String())
+			}
+			a.Equal(tt.paths, paths)
+		})
+	}
+
+	t.Run("Elems", func(t *testing.T) {
+		a := require.New(t)
+
+		var v T
+		err := yaml.Unmarshal([]byte("spec: {containers: [{name: a, ports: [{containerPort: x}]}]}"), &v)
+		var uerr *yaml.UnmarshalError
+		a.ErrorAs(err, &uerr)
+		a.Equal(yaml.Path{
+			{Key: "spec"},
+			{Key: "containers"},
+			{Index: 0, IsIndex: true},
+			{Key: "ports"},
+			{Index: 0, IsIndex: true},
+			{Key: "containerPort"},
+		}, uerr.Path)
+
+		err = yaml.UnmarshalWithOptions([]byte("spec: {unknown: 1}"), &v, yaml.DecodeOptions{KnownFields: true})
+		var ferr *yaml.UnknownFieldError
+		a.ErrorAs(err, &ferr)
+		a.Equal("spec.unknown", ferr.Path.String())
+
+		err = yaml.Unmarshal([]byte("spec: {labels: {a: 1, a: 2}}"), &v)
+		var derr *yaml.DuplicateKeyError
+		a.ErrorAs(err, &derr)
+		a.Equal("spec.labels.a", derr.Path.String())
+	})
+}
+
 type textUnmarshaler struct {
 	S string
 }
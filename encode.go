@@ -16,6 +16,7 @@
 package yaml
 
 import (
+	"context"
 	"encoding"
 	"fmt"
 	"io"
@@ -29,12 +30,48 @@ import (
 )
 
 type encoder struct {
-	emitter  yaml_emitter_t
-	event    yaml_event_t
-	out      []byte
-	flow     bool
-	indent   int
-	doneInit bool
+	emitter   yaml_emitter_t
+	event     yaml_event_t
+	out       []byte
+	flow      bool
+	style     yaml_scalar_style_t
+	indent    int
+	width     int
+	canonical bool
+	asciiOnly bool
+	lineBreak LineBreak
+	keyLess   func(a, b any) bool
+	keyOrder  map[string]int
+	jsonTags  bool
+	tags      map[reflect.Type]tagEncoder
+	doneInit  bool
+
+	// anchors enables anchors for values referenced more than once.
+	anchors     bool
+	refs        map[refKey]*encoderRef
+	visiting    map[refKey]struct{}
+	depth       int
+	anchorCount int
+	// anchor is the value to be anchored at the next emitted node.
+	anchor *encoderRef
+
+	// ctx is the context passed to MarshalerContext.
+	ctx context.Context
+
+	// headComment and lineComment are comments of the next emitted scalar.
+	headComment string
+	lineComment string
+
+	// bufferSize is the size of the output buffer.
+	bufferSize int
+	// stream holds collections opened by Encoder.BeginSequence and
+	// Encoder.BeginMapping.
+	stream []streamFrame
+}
+
+type tagEncoder struct {
+	tag string
+	f   TagEncodeFunc
 }
 
 func newEncoder() *encoder {
@@ -49,6 +86,39 @@ func newEncoderWithWriter(w io.Writer) *encoder {
 	return e
 }
 
+func (e *encoder) setOptions(opts EncodeOptions) {
+	e.width = opts.Width
+	e.canonical = opts.Canonical
+	e.asciiOnly = opts.ASCIIOnly
+	e.lineBreak = opts.LineBreak
+	e.keyLess = opts.KeyLess
+	e.keyOrder = keyOrder(opts.KeyOrder)
+	e.anchors = opts.Anchors
+	e.jsonTags = opts.JSONTags
+}
+
+// keyOrder returns the rank of given keys.
+func keyOrder(keys []string) map[string]int {
+	if len(keys) == 0 {
+		return nil
+	}
+	r := make(map[string]int, len(keys))
+	for i, k := range keys {
+		if _, ok := r[k]; !ok {
+			r[k] = i
+		}
+	}
+	return r
+}
+
+// context returns the context of the encoding.
+func (e *encoder) context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
 func (e *encoder) init() {
 	if e.doneInit {
 		return
@@ -57,6 +127,15 @@ func (e *encoder) init() {
 		e.indent = 4
 	}
 	e.emitter.best_indent = e.indent
+	if e.bufferSize > len(e.emitter.buffer) {
+		e.emitter.buffer = append(e.emitter.buffer[:0], make([]byte, e.bufferSize)...)
+	}
+	if e.width > 0 {
+		yaml_emitter_set_width(&e.emitter, e.width)
+	}
+	yaml_emitter_set_canonical(&e.emitter, e.canonical)
+	yaml_emitter_set_unicode(&e.emitter, !e.asciiOnly)
+	yaml_emitter_set_break(&e.emitter, e.lineBreak.yaml())
 	yaml_stream_start_event_initialize(&e.event, yaml_UTF8_ENCODING)
 	e.emit()
 	e.doneInit = true
@@ -99,6 +178,12 @@ func (e *encoder) marshalDoc(tag string, in reflect.Value) {
 	if node != nil && node.Kind == DocumentNode {
 		e.nodev(in)
 	} else {
+		if e.anchors {
+			// Anchors are scoped to the document.
+			e.refs = map[refKey]*encoderRef{}
+			e.anchorCount = 0
+			e.countRefs(in)
+		}
 		yaml_document_start_event_initialize(&e.event, nil, nil, true)
 		e.emit()
 		e.marshal(tag, in)
@@ -116,6 +201,18 @@ func (e *encoder) marshal(tag string, in reflect.Value) {
 		e.nilv()
 		return
 	}
+	if key, ok := refKeyOf(in); ok {
+		if !e.enter(key, in) {
+			return
+		}
+		defer e.leave(key)
+	}
+	if tag == "" && len(e.tags) > 0 {
+		if te, ok := e.tags[in.Type()]; ok {
+			e.taggedv(te, in)
+			return
+		}
+	}
 	// FIXME(tdakkota): get rid of Interface use.
 	if in.CanInterface() {
 		iface := in.Interface()
@@ -131,6 +228,9 @@ func (e *encoder) marshal(tag string, in reflect.Value) {
 		case *Node:
 			e.nodev(in)
 			return
+		case MapSlice:
+			e.mapSlicev(tag, value)
+			return
 		case time.Time:
 			e.timev(tag, in)
 			return
@@ -140,6 +240,17 @@ func (e *encoder) marshal(tag string, in reflect.Value) {
 		case time.Duration:
 			e.stringv(tag, reflect.ValueOf(value.String()))
 			return
+		case MarshalerContext:
+			v, err := value.MarshalYAML(e.context())
+			if err != nil {
+				fail(err)
+			}
+			if v == nil {
+				e.nilv()
+				return
+			}
+			e.marshal(tag, reflect.ValueOf(v))
+			return
 		case Marshaler:
 			v, err := value.MarshalYAML()
 			if err != nil {
@@ -183,10 +294,34 @@ func (e *encoder) marshal(tag string, in reflect.Value) {
 	}
 }
 
+func (e *encoder) taggedv(te tagEncoder, in reflect.Value) {
+	if te.f != nil {
+		v, err := te.f(in)
+		if err != nil {
+			fail(err)
+		}
+		switch v := v.(type) {
+		case nil:
+			e.nilv()
+			return
+		case *Node:
+			kopy := *v
+			kopy.Tag = te.tag
+			e.node(&kopy, "")
+			return
+		case Node:
+			v.Tag = te.tag
+			e.node(&v, "")
+			return
+		}
+		in = reflect.ValueOf(v)
+	}
+	e.marshal(te.tag, in)
+}
+
 func (e *encoder) mapv(tag string, in reflect.Value) {
 	e.mappingv(tag, func() {
-		keys := keyList(in.MapKeys())
-		sort.Sort(keys)
+		keys := e.sortedKeys(in)
 		for _, k := range keys {
 			e.marshal("", k)
 			e.marshal("", in.MapIndex(k))
@@ -194,6 +329,42 @@ func (e *encoder) mapv(tag string, in reflect.Value) {
 	})
 }
 
+func (e *encoder) mapSlicev(tag string, in MapSlice) {
+	e.mappingv(tag, func() {
+		for _, item := range in {
+			e.marshal("", reflect.ValueOf(item.Key))
+			e.marshal("", reflect.ValueOf(item.Value))
+		}
+	})
+}
+
+// sortedKeys returns the keys of given map in the order of emitting.
+func (e *encoder) sortedKeys(m reflect.Value) []reflect.Value {
+	keys := keyList(m.MapKeys())
+	if e.keyLess == nil && e.keyOrder == nil {
+		sort.Sort(keys)
+		return keys
+	}
+
+	rank := func(k reflect.Value) int {
+		for k.Kind() == reflect.Interface && !k.IsNil() {
+			k = k.Elem()
+		}
+		if k.Kind() == reflect.String {
+			if r, ok := e.keyOrder[k.String()]; ok {
+				return r
+			}
+		}
+		return len(e.keyOrder)
+	}
+	sort.Sort(rankedKeyList{
+		keys: keys,
+		rank: rank,
+		less: e.keyLess,
+	})
+	return keys
+}
+
 func (e *encoder) fieldByIndex(v reflect.Value, index []int) (field reflect.Value) {
 	for _, num := range index {
 		for {
@@ -212,7 +383,7 @@ func (e *encoder) fieldByIndex(v reflect.Value, index []int) (field reflect.Valu
 }
 
 func (e *encoder) structv(tag string, in reflect.Value) {
-	sinfo, err := getStructInfo(in.Type())
+	sinfo, err := getStructInfo(in.Type(), e.jsonTags)
 	if err != nil {
 		panic(err)
 	}
@@ -230,16 +401,22 @@ func (e *encoder) structv(tag string, in reflect.Value) {
 			if info.OmitEmpty && isZero(value) {
 				continue
 			}
+			e.headComment = info.HeadComment
+			e.lineComment = info.LineComment
 			e.marshal("", reflect.ValueOf(info.Key))
+			if info.String && e.quotedv(value) {
+				continue
+			}
 			e.flow = info.Flow
+			e.style = info.Style
 			e.marshal("", value)
+			e.style = yaml_ANY_SCALAR_STYLE
 		}
 		if sinfo.InlineMap >= 0 {
 			m := in.Field(sinfo.InlineMap)
 			if m.Len() > 0 {
 				e.flow = false
-				keys := keyList(m.MapKeys())
-				sort.Sort(keys)
+				keys := e.sortedKeys(m)
 				for _, k := range keys {
 					key := k.String()
 					if f, ok := sinfo.FieldsMap[key]; ok {
@@ -258,13 +435,18 @@ func (e *encoder) structv(tag string, in reflect.Value) {
 }
 
 func (e *encoder) mappingv(tag string, f func()) {
+	// Scalar style applies to the value itself, not to its items.
+	e.style = yaml_ANY_SCALAR_STYLE
+	if tag == "" && e.canonical {
+		tag = longTag(mapTag)
+	}
 	implicit := tag == ""
 	style := yaml_BLOCK_MAPPING_STYLE
 	if e.flow {
 		e.flow = false
 		style = yaml_FLOW_MAPPING_STYLE
 	}
-	yaml_mapping_start_event_initialize(&e.event, nil, []byte(tag), implicit, style)
+	yaml_mapping_start_event_initialize(&e.event, e.takeAnchor(), []byte(tag), implicit, style)
 	e.emit()
 	f()
 	yaml_mapping_end_event_initialize(&e.event)
@@ -272,13 +454,17 @@ func (e *encoder) mappingv(tag string, f func()) {
 }
 
 func (e *encoder) slicev(tag string, in reflect.Value) {
+	e.style = yaml_ANY_SCALAR_STYLE
+	if tag == "" && e.canonical {
+		tag = longTag(seqTag)
+	}
 	implicit := tag == ""
 	style := yaml_BLOCK_SEQUENCE_STYLE
 	if e.flow {
 		e.flow = false
 		style = yaml_FLOW_SEQUENCE_STYLE
 	}
-	e.must(yaml_sequence_start_event_initialize(&e.event, nil, []byte(tag), implicit, style))
+	e.must(yaml_sequence_start_event_initialize(&e.event, e.takeAnchor(), []byte(tag), implicit, style))
 	e.emit()
 	n := in.Len()
 	for i := 0; i < n; i++ {
@@ -363,6 +549,14 @@ func (e *encoder) stringv(tag string, in reflect.Value) {
 	default:
 		style = yaml_DOUBLE_QUOTED_SCALAR_STYLE
 	}
+	if e.style != yaml_ANY_SCALAR_STYLE {
+		// The emitter falls back to a style which can represent
+		// the value, if the requested one can't.
+		if tag != binaryTag {
+			style = e.style
+		}
+		e.style = yaml_ANY_SCALAR_STYLE
+	}
 	e.emitScalar(s, "", tag, style, nil, nil, nil, nil)
 }
 
@@ -386,6 +580,40 @@ func (e *encoder) uintv(tag string, in reflect.Value) {
 	e.emitScalar(s, "", tag, yaml_PLAIN_SCALAR_STYLE, nil, nil, nil, nil)
 }
 
+// quotedv emits numeric or boolean value as a double-quoted string.
+//
+// It returns false if the value should be marshaled as usual, e.g.
+// if it implements Marshaler.
+func (e *encoder) quotedv(in reflect.Value) bool {
+	if in.Kind() == reflect.Ptr {
+		if in.IsNil() {
+			return false
+		}
+		in = in.Elem()
+	}
+	if in.CanInterface() {
+		switch in.Interface().(type) {
+		case Marshaler, MarshalerContext, encoding.TextMarshaler:
+			return false
+		}
+	}
+	var s string
+	switch in.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(in.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(in.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = formatFloat(in)
+	case reflect.Bool:
+		s = strconv.FormatBool(in.Bool())
+	default:
+		return false
+	}
+	e.emitScalar(s, "", "", yaml_DOUBLE_QUOTED_SCALAR_STYLE, nil, nil, nil, nil)
+	return true
+}
+
 func (e *encoder) timev(tag string, in reflect.Value) {
 	t := in.Interface().(time.Time)
 	s := t.Format(time.RFC3339Nano)
@@ -393,6 +621,11 @@ func (e *encoder) timev(tag string, in reflect.Value) {
 }
 
 func (e *encoder) floatv(tag string, in reflect.Value) {
+	s := formatFloat(in)
+	e.emitScalar(s, "", tag, yaml_PLAIN_SCALAR_STYLE, nil, nil, nil, nil)
+}
+
+func formatFloat(in reflect.Value) string {
 	// Issue #352: When formatting, use the precision of the underlying value
 	precision := 64
 	if in.Kind() == reflect.Float32 {
@@ -408,7 +641,7 @@ func (e *encoder) floatv(tag string, in reflect.Value) {
 	case "NaN":
 		s = ".nan"
 	}
-	e.emitScalar(s, "", tag, yaml_PLAIN_SCALAR_STYLE, nil, nil, nil, nil)
+	return s
 }
 
 func (e *encoder) nilv() {
@@ -417,10 +650,27 @@ func (e *encoder) nilv() {
 
 func (e *encoder) emitScalar(value, anchor, tag string, style yaml_scalar_style_t, head, line, foot, tail []byte) {
 	// TODO Kill this function. Replace all initialize calls by their underlining Go literals.
+	if tag == "" && e.canonical {
+		// Canonical output has explicit tags everywhere.
+		tag = strTag
+		if style == yaml_PLAIN_SCALAR_STYLE {
+			tag, _ = resolve("", value)
+		}
+	}
 	implicit := tag == ""
 	if !implicit {
 		tag = longTag(tag)
 	}
+	if anchor == "" {
+		anchor = string(e.takeAnchor())
+	}
+	if head == nil && e.headComment != "" {
+		head = []byte(e.headComment)
+	}
+	if line == nil && e.lineComment != "" {
+		line = []byte(e.lineComment)
+	}
+	e.headComment, e.lineComment = "", ""
 	e.must(yaml_scalar_event_initialize(&e.event, []byte(anchor), []byte(tag), []byte(value), implicit, implicit, style))
 	e.event.head_comment = head
 	e.event.line_comment = line
@@ -439,6 +689,10 @@ func (e *encoder) node(node *Node, tail string) {
 		e.nilv()
 		return
 	}
+	anchor := node.Anchor
+	if node.Kind != DocumentNode {
+		anchor = e.nodeAnchor(node)
+	}
 
 	// If the tag was not explicitly requested, and dropping it won't change the
 	// implicit tag of the value, don't include it in the presentation.
@@ -489,7 +743,10 @@ func (e *encoder) node(node *Node, tail string) {
 		if node.Style&FlowStyle != 0 {
 			style = yaml_FLOW_SEQUENCE_STYLE
 		}
-		e.must(yaml_sequence_start_event_initialize(&e.event, []byte(node.Anchor), []byte(longTag(tag)), tag == "", style))
+		if tag == "" && e.canonical {
+			tag = seqTag
+		}
+		e.must(yaml_sequence_start_event_initialize(&e.event, []byte(anchor), []byte(longTag(tag)), tag == "", style))
 		e.event.head_comment = []byte(node.HeadComment)
 		e.emit()
 		for _, node := range node.Content {
@@ -505,7 +762,10 @@ func (e *encoder) node(node *Node, tail string) {
 		if node.Style&FlowStyle != 0 {
 			style = yaml_FLOW_MAPPING_STYLE
 		}
-		yaml_mapping_start_event_initialize(&e.event, []byte(node.Anchor), []byte(longTag(tag)), tag == "", style)
+		if tag == "" && e.canonical {
+			tag = mapTag
+		}
+		yaml_mapping_start_event_initialize(&e.event, []byte(anchor), []byte(longTag(tag)), tag == "", style)
 		e.event.tail_comment = []byte(tail)
 		e.event.head_comment = []byte(node.HeadComment)
 		e.emit()
@@ -574,7 +834,7 @@ func (e *encoder) node(node *Node, tail string) {
 			style = yaml_DOUBLE_QUOTED_SCALAR_STYLE
 		}
 
-		e.emitScalar(value, node.Anchor, tag, style, []byte(node.HeadComment), []byte(node.LineComment), []byte(node.FootComment), []byte(tail))
+		e.emitScalar(value, anchor, tag, style, []byte(node.HeadComment), []byte(node.LineComment), []byte(node.FootComment), []byte(tail))
 	default:
 		fail(&MarshalError{Msg: fmt.Sprintf("cannot encode node with unknown kind %d", node.Kind)})
 	}
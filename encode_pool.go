@@ -32,6 +32,26 @@ func (e *encoder) reset() {
 	e.event = yaml_event_t{}
 	e.out = nil
 	e.flow = false
+	e.style = yaml_ANY_SCALAR_STYLE
 	e.indent = 0
+	e.width = 0
+	e.canonical = false
+	e.asciiOnly = false
+	e.lineBreak = LineBreakLF
+	e.keyLess = nil
+	e.keyOrder = nil
+	e.jsonTags = false
+	e.tags = nil
+	e.anchors = false
+	e.refs = nil
+	e.visiting = nil
+	e.depth = 0
+	e.anchor = nil
+	e.anchorCount = 0
+	e.ctx = nil
+	e.headComment = ""
+	e.lineComment = ""
+	e.bufferSize = 0
+	e.stream = e.stream[:0]
 	e.doneInit = false
 }
@@ -0,0 +1,180 @@
+package yaml
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// startDetectingCyclesAfter is the nesting depth after which the encoder
+// starts to track visited values to detect cycles, unless anchors are
+// enabled.
+//
+// Like encoding/json, this avoids the cost of tracking for typical values.
+const startDetectingCyclesAfter = 1000
+
+// refKey identifies a pointer, map or slice value.
+type refKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// encoderRef holds the state of a value referenced by a pointer, map or slice.
+type encoderRef struct {
+	count  int
+	anchor string
+}
+
+// refKeyOf returns the identity of the value, if it has one.
+func refKeyOf(in reflect.Value) (refKey, bool) {
+	switch in.Kind() {
+	case reflect.Ptr:
+		if in.IsNil() || in.Type() == ptrNodeType {
+			return refKey{}, false
+		}
+	case reflect.Map:
+		if in.IsNil() || in.Len() == 0 {
+			return refKey{}, false
+		}
+	case reflect.Slice:
+		if in.IsNil() || in.Len() == 0 {
+			return refKey{}, false
+		}
+		return refKey{ptr: in.Pointer(), typ: in.Type(), len: in.Len()}, true
+	default:
+		return refKey{}, false
+	}
+	return refKey{ptr: in.Pointer(), typ: in.Type()}, true
+}
+
+// enter marks the value as being encoded.
+//
+// It returns false if the value was emitted as an alias.
+func (e *encoder) enter(key refKey, in reflect.Value) bool {
+	e.depth++
+	if !e.anchors && e.depth <= startDetectingCyclesAfter {
+		return true
+	}
+	if _, ok := e.visiting[key]; ok {
+		fail(&MarshalError{Msg: fmt.Sprintf("encountered a cycle via %s", in.Type())})
+	}
+	if r := e.refs[key]; r != nil && r.count > 1 {
+		if r.anchor != "" {
+			e.depth--
+			e.aliasv(r.anchor)
+			return false
+		}
+		e.anchorCount++
+		r.anchor = fmt.Sprintf("id%03d", e.anchorCount)
+		e.anchor = r
+	}
+	if e.visiting == nil {
+		e.visiting = map[refKey]struct{}{}
+	}
+	e.visiting[key] = struct{}{}
+	return true
+}
+
+// leave marks the value as encoded.
+func (e *encoder) leave(key refKey) {
+	e.depth--
+	delete(e.visiting, key)
+}
+
+// countRefs counts references to pointers, maps and slices in the value,
+// so that values referenced more than once get an anchor.
+func (e *encoder) countRefs(in reflect.Value) {
+	for in.Kind() == reflect.Interface {
+		if in.IsNil() {
+			return
+		}
+		in = in.Elem()
+	}
+	if !in.IsValid() {
+		return
+	}
+	if key, ok := refKeyOf(in); ok {
+		r := e.refs[key]
+		if r == nil {
+			r = &encoderRef{}
+			e.refs[key] = r
+		}
+		r.count++
+		if r.count > 1 {
+			// Already visited.
+			return
+		}
+	}
+	if in.CanInterface() {
+		switch in.Interface().(type) {
+		case Node, *Node, time.Time, *time.Time, Marshaler, MarshalerContext, encoding.TextMarshaler:
+			return
+		}
+	}
+	switch in.Kind() {
+	case reflect.Ptr:
+		e.countRefs(in.Elem())
+	case reflect.Map:
+		iter := in.MapRange()
+		for iter.Next() {
+			e.countRefs(iter.Value())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < in.Len(); i++ {
+			e.countRefs(in.Index(i))
+		}
+	case reflect.Struct:
+		sinfo, err := getStructInfo(in.Type(), e.jsonTags)
+		if err != nil {
+			panic(err)
+		}
+		for _, info := range sinfo.FieldsList {
+			if info.Inline == nil {
+				e.countRefs(in.Field(info.Num))
+			} else if value := e.fieldByIndex(in, info.Inline); value.IsValid() {
+				e.countRefs(value)
+			}
+		}
+		if sinfo.InlineMap >= 0 {
+			e.countRefs(in.Field(sinfo.InlineMap))
+		}
+	}
+}
+
+// takeAnchor returns the anchor of the next emitted node and resets it.
+func (e *encoder) takeAnchor() []byte {
+	if e.anchor == nil {
+		return nil
+	}
+	anchor := e.anchor.anchor
+	e.anchor = nil
+	return []byte(anchor)
+}
+
+// nodeAnchor returns the anchor of the node encoded for a value to be
+// anchored. If the node has its own anchor, aliases of the value refer
+// to it instead.
+func (e *encoder) nodeAnchor(node *Node) string {
+	r := e.anchor
+	if r == nil {
+		return node.Anchor
+	}
+	e.anchor = nil
+	switch {
+	case node.Kind == AliasNode:
+		r.anchor = node.Value
+		return ""
+	case node.Anchor != "":
+		r.anchor = node.Anchor
+		return node.Anchor
+	default:
+		return r.anchor
+	}
+}
+
+func (e *encoder) aliasv(anchor string) {
+	yaml_alias_event_initialize(&e.event, []byte(anchor))
+	e.emit()
+}
@@ -0,0 +1,165 @@
+package yaml
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// streamFrame is a collection opened by Encoder.BeginSequence or
+// Encoder.BeginMapping.
+type streamFrame struct {
+	kind  Kind
+	items int
+}
+
+// BeginSequence starts a block sequence, which is filled item by item by
+// EncodeItem and terminated by EndSequence.
+//
+// Unlike Encode, it does not require the whole value to be in memory,
+// for example:
+//
+//	enc.BeginSequence()
+//	for rows.Next() {
+//	    enc.EncodeItem(row)
+//	}
+//	enc.EndSequence()
+//
+// If no collection is open, the sequence starts a new document. Otherwise,
+// the sequence is an item of the innermost open collection.
+//
+// Encoded data is written to the underlying writer once the output
+// buffer is full, see SetBufferSize.
+func (e *Encoder) BeginSequence() (err error) {
+	defer handleErr(&err)
+	e.encoder.beginCollection(SequenceNode)
+	return nil
+}
+
+// EndSequence terminates the sequence started by BeginSequence.
+func (e *Encoder) EndSequence() (err error) {
+	defer handleErr(&err)
+	e.encoder.endCollection(SequenceNode)
+	return nil
+}
+
+// BeginMapping starts a block mapping, which is filled by EncodeItem and
+// terminated by EndMapping. Items are keys and values in turn.
+//
+// It allows to stream a sequence nested in a mapping, for example:
+//
+//	enc.BeginMapping()
+//	enc.EncodeItem("items")
+//	enc.BeginSequence()
+//	// ...
+//	enc.EndSequence()
+//	enc.EndMapping()
+//
+// If no collection is open, the mapping starts a new document. Otherwise,
+// the mapping is an item of the innermost open collection.
+func (e *Encoder) BeginMapping() (err error) {
+	defer handleErr(&err)
+	e.encoder.beginCollection(MappingNode)
+	return nil
+}
+
+// EndMapping terminates the mapping started by BeginMapping.
+func (e *Encoder) EndMapping() (err error) {
+	defer handleErr(&err)
+	e.encoder.endCollection(MappingNode)
+	return nil
+}
+
+// EncodeItem writes the YAML encoding of v as an item of the innermost
+// collection opened by BeginSequence or BeginMapping.
+//
+// See the documentation for Marshal for details about the conversion of Go
+// values to YAML. If anchors are enabled, aliases can refer only to values
+// within the same item.
+func (e *Encoder) EncodeItem(v any) (err error) {
+	defer handleErr(&err)
+	e.encoder.streamItem(reflect.ValueOf(v))
+	return nil
+}
+
+func (e *encoder) beginCollection(kind Kind) {
+	if len(e.stream) == 0 {
+		e.init()
+		e.anchorCount = 0
+		yaml_document_start_event_initialize(&e.event, nil, nil, true)
+		e.emit()
+	} else {
+		e.stream[len(e.stream)-1].items++
+	}
+
+	var tag string
+	switch kind {
+	case SequenceNode:
+		if e.canonical {
+			tag = longTag(seqTag)
+		}
+		e.must(yaml_sequence_start_event_initialize(&e.event, nil, []byte(tag), tag == "", yaml_BLOCK_SEQUENCE_STYLE))
+	case MappingNode:
+		if e.canonical {
+			tag = longTag(mapTag)
+		}
+		yaml_mapping_start_event_initialize(&e.event, nil, []byte(tag), tag == "", yaml_BLOCK_MAPPING_STYLE)
+	}
+	e.emit()
+	e.stream = append(e.stream, streamFrame{kind: kind})
+}
+
+func (e *encoder) endCollection(kind Kind) {
+	if len(e.stream) == 0 {
+		fail(&MarshalError{Msg: fmt.Sprintf("no open %s to end", kindName(kind))})
+	}
+	top := e.stream[len(e.stream)-1]
+	if top.kind != kind {
+		fail(&MarshalError{Msg: fmt.Sprintf("cannot end %s, %s is open", kindName(kind), kindName(top.kind))})
+	}
+	switch kind {
+	case SequenceNode:
+		e.must(yaml_sequence_end_event_initialize(&e.event))
+	case MappingNode:
+		if top.items%2 != 0 {
+			fail(&MarshalError{Msg: "mapping key has no value"})
+		}
+		yaml_mapping_end_event_initialize(&e.event)
+	}
+	e.emit()
+	e.stream = e.stream[:len(e.stream)-1]
+
+	if len(e.stream) == 0 {
+		yaml_document_end_event_initialize(&e.event, true)
+		e.emit()
+	}
+}
+
+func (e *encoder) streamItem(in reflect.Value) {
+	if len(e.stream) == 0 {
+		fail(&MarshalError{Msg: "no open sequence or mapping to encode item to"})
+	}
+	e.stream[len(e.stream)-1].items++
+	if e.anchors {
+		// Anchors are scoped to the item, since other items
+		// are not available.
+		e.refs = map[refKey]*encoderRef{}
+		e.countRefs(in)
+	}
+	e.marshal("", in)
+}
+
+// checkStream fails if a collection opened by BeginSequence or BeginMapping
+// is not terminated.
+func (e *encoder) checkStream() {
+	if len(e.stream) > 0 {
+		top := e.stream[len(e.stream)-1]
+		fail(&MarshalError{Msg: fmt.Sprintf("unterminated %s", kindName(top.kind))})
+	}
+}
+
+func kindName(kind Kind) string {
+	if kind == MappingNode {
+		return "mapping"
+	}
+	return "sequence"
+}
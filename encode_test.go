@@ -16,6 +16,8 @@
 package yaml_test
 
 import (
+	"bytes"
+	"context"
 	"encoding"
 	"fmt"
 	"math"
@@ -1038,6 +1040,109 @@ func TestSetIndent(t *testing.T) {
 	a.Equal("a:\n        b:\n                c: d\n", buf.String())
 }
 
+type jsonTagsEmbedded struct {
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type jsonTagsValue struct {
+	jsonTagsEmbedded
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind,omitempty,omitzero"`
+	Replicas   int               `json:",omitempty"`
+	Secret     string            `json:"-"`
+	Dash       string            `json:"-,"`
+	Port       int               `json:"port,string"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"meta,flow"`
+	Plain      string
+}
+
+func TestMarshalJSONTags(t *testing.T) {
+	a := require.New(t)
+
+	v := jsonTagsValue{
+		jsonTagsEmbedded: jsonTagsEmbedded{Namespace: "default"},
+		APIVersion:       "v1",
+		Replicas:         3,
+		Secret:           "secret",
+		Dash:             "dash",
+		Port:             80,
+		Labels:           map[string]string{"app": "web"},
+		Plain:            "plain",
+	}
+
+	data, err := yaml.MarshalWithOptions(v, yaml.EncodeOptions{JSONTags: true})
+	a.NoError(err)
+	a.Equal("namespace: default\n"+
+		"apiVersion: v1\n"+
+		"Replicas: 3\n"+
+		"'-': dash\n"+
+		"port: \"80\"\n"+
+		"meta: {app: web}\n"+
+		"plain: plain\n", string(data))
+
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.UseJSONTags(true)
+	a.NoError(enc.Encode(v))
+	a.NoError(enc.Close())
+	a.Equal(string(data), buf.String())
+
+	// JSON tags are ignored by default.
+	data, err = yaml.Marshal(jsonTagsEmbedded{Namespace: "default"})
+	a.NoError(err)
+	a.Equal("namespace: default\n", string(data))
+	data, err = yaml.Marshal(struct {
+		Kind string `json:"kind"`
+		B    string `json:"b,omitempty"`
+	}{Kind: "Pod"})
+	a.NoError(err)
+	a.Equal("kind: Pod\nb: \"\"\n", string(data))
+}
+
+type secretRef struct {
+	Name string
+}
+
+type regexValue struct {
+	Pattern string
+}
+
+func TestEncoderRegisterTag(t *testing.T) {
+	a := require.New(t)
+
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.RegisterTag("!secret", reflect.TypeOf(secretRef{}), func(v reflect.Value) (any, error) {
+		return v.Interface().(secretRef).Name, nil
+	})
+	enc.RegisterTag("!regex", reflect.TypeOf(regexValue{}), func(v reflect.Value) (any, error) {
+		return &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Style: yaml.SingleQuotedStyle,
+			Value: v.Interface().(regexValue).Pattern,
+		}, nil
+	})
+	enc.RegisterTag("!ref", reflect.TypeOf(map[string]int{}), nil)
+
+	a.NoError(enc.Encode(struct {
+		Password *secretRef
+		Pattern  regexValue
+		Ref      map[string]int
+		Nil      *secretRef
+	}{
+		Password: &secretRef{Name: "db"},
+		Pattern:  regexValue{Pattern: "^a+$"},
+		Ref:      map[string]int{"a": 1},
+	}))
+	a.NoError(enc.Close())
+	a.Equal("password: !secret db\n"+
+		"pattern: !regex '^a+$'\n"+
+		"ref:\n"+
+		"    !ref\n"+
+		"    a: 1\n"+
+		"nil: null\n", buf.String())
+}
+
 func TestSortedOutput(t *testing.T) {
 	a := require.New(t)
 
@@ -1118,6 +1223,546 @@ func TestSortedOutput(t *testing.T) {
 	}
 }
 
+func TestMarshalStringFlag(t *testing.T) {
+	a := require.New(t)
+
+	type T struct {
+		Port    int           `yaml:"port,string"`
+		Ratio   float32       `yaml:"ratio,string"`
+		Enabled bool          `yaml:"enabled,string"`
+		Size    *uint         `yaml:"size,string"`
+		Nil     *int          `yaml:"nil,string"`
+		Timeout time.Duration `yaml:"timeout,string"`
+		Name    string        `yaml:"name,string"`
+		Plain   int           `yaml:"plain"`
+	}
+	v := T{
+		Port:    8080,
+		Ratio:   0.5,
+		Enabled: true,
+		Size:    ptrTo(uint(10)),
+		Timeout: time.Second,
+		Name:    "a",
+		Plain:   1,
+	}
+	data, err := yaml.Marshal(v)
+	a.NoError(err)
+	a.Equal("port: \"8080\"\n"+
+		"ratio: \"0.5\"\n"+
+		"enabled: \"true\"\n"+
+		"size: \"10\"\n"+
+		"nil: null\n"+
+		"timeout: 1s\n"+
+		"name: a\n"+
+		"plain: 1\n", string(data))
+
+	var decoded T
+	a.NoError(yaml.Unmarshal(data, &decoded))
+	a.Equal(v, decoded)
+}
+
+func TestEncoderFormatting(t *testing.T) {
+	v := map[string]any{
+		"a": "héllo wörld, this is a long line",
+		"b": []int{1, 2},
+	}
+	tests := []struct {
+		opts   yaml.EncodeOptions
+		expect string
+	}{
+		{
+			yaml.EncodeOptions{},
+			"a: héllo wörld, this is a long line\nb:\n    - 1\n    - 2\n",
+		},
+		{
+			yaml.EncodeOptions{Width: 20},
+			"a: héllo wörld, this is\n    a long line\nb:\n    - 1\n    - 2\n",
+		},
+		{
+			yaml.EncodeOptions{ASCIIOnly: true},
+			"a: \"h\\xE9llo w\\xF6rld, this is a long line\"\nb:\n    - 1\n    - 2\n",
+		},
+		{
+			yaml.EncodeOptions{LineBreak: yaml.LineBreakCRLF},
+			"a: héllo wörld, this is a long line\r\nb:\r\n    - 1\r\n    - 2\r\n",
+		},
+		{
+			yaml.EncodeOptions{LineBreak: yaml.LineBreakCR},
+			"a: héllo wörld, this is a long line\rb:\r    - 1\r    - 2\r",
+		},
+		{
+			yaml.EncodeOptions{Canonical: true},
+			"---\n!!map {\n" +
+				"    ? !!str \"a\"\n    : !!str \"héllo wörld, this is a long line\",\n" +
+				"    ? !!str \"b\"\n    : !!seq [\n        !!int \"1\",\n        !!int \"2\",\n    ],\n" +
+				"}\n",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			data, err := yaml.MarshalWithOptions(v, tt.opts)
+			a.NoError(err)
+			a.Equal(tt.expect, string(data))
+
+			var buf strings.Builder
+			enc := yaml.NewEncoder(&buf)
+			enc.SetWidth(tt.opts.Width)
+			enc.SetCanonical(tt.opts.Canonical)
+			enc.SetASCIIOnly(tt.opts.ASCIIOnly)
+			enc.SetLineBreak(tt.opts.LineBreak)
+			a.NoError(enc.Encode(v))
+			a.NoError(enc.Close())
+			a.Equal(tt.expect, buf.String())
+
+			var decoded map[string]any
+			a.NoError(yaml.Unmarshal(data, &decoded))
+			a.Equal(v["a"], decoded["a"])
+			a.Equal([]any{1, 2}, decoded["b"])
+		})
+	}
+}
+
+func TestEncoderKeyOrder(t *testing.T) {
+	manifest := map[string]any{
+		"spec":       map[string]any{"replicas": 1, "a10": 1, "a2": 2},
+		"metadata":   map[string]any{"name": "web"},
+		"kind":       "Deployment",
+		"apiVersion": "apps/v1",
+		"status":     nil,
+	}
+	reverse := func(a, b any) bool {
+		return fmt.Sprint(a) > fmt.Sprint(b)
+	}
+
+	tests := []struct {
+		opts   yaml.EncodeOptions
+		expect string
+	}{
+		{
+			yaml.EncodeOptions{},
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n    name: web\n" +
+				"spec:\n    a2: 2\n    a10: 1\n    replicas: 1\nstatus: null\n",
+		},
+		{
+			yaml.EncodeOptions{KeyLess: reverse},
+			"status: null\nspec:\n    replicas: 1\n    a2: 2\n    a10: 1\n" +
+				"metadata:\n    name: web\nkind: Deployment\napiVersion: apps/v1\n",
+		},
+		{
+			yaml.EncodeOptions{KeyOrder: []string{"kind", "apiVersion", "metadata", "replicas"}},
+			"kind: Deployment\napiVersion: apps/v1\nmetadata:\n    name: web\n" +
+				"spec:\n    replicas: 1\n    a2: 2\n    a10: 1\nstatus: null\n",
+		},
+		{
+			yaml.EncodeOptions{KeyOrder: []string{"metadata"}, KeyLess: reverse},
+			"metadata:\n    name: web\nstatus: null\nspec:\n    replicas: 1\n    a2: 2\n    a10: 1\n" +
+				"kind: Deployment\napiVersion: apps/v1\n",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			data, err := yaml.MarshalWithOptions(manifest, tt.opts)
+			a.NoError(err)
+			a.Equal(tt.expect, string(data))
+
+			var buf strings.Builder
+			enc := yaml.NewEncoder(&buf)
+			enc.SetKeyLess(tt.opts.KeyLess)
+			enc.SetKeyOrder(tt.opts.KeyOrder...)
+			a.NoError(enc.Encode(manifest))
+			a.NoError(enc.Close())
+			a.Equal(tt.expect, buf.String())
+		})
+	}
+
+	t.Run("Inline", func(t *testing.T) {
+		a := require.New(t)
+
+		v := struct {
+			Name  string         `yaml:"name"`
+			Extra map[string]int `yaml:",inline"`
+		}{
+			Name:  "a",
+			Extra: map[string]int{"x": 1, "w": 3, "z": 4},
+		}
+		data, err := yaml.MarshalWithOptions(v, yaml.EncodeOptions{KeyOrder: []string{"z", "name"}})
+		a.NoError(err)
+		a.Equal("name: a\nz: 4\nw: 3\nx: 1\n", string(data))
+	})
+}
+
+func TestEncoderAnchors(t *testing.T) {
+	type Config struct {
+		Name string `yaml:"name"`
+	}
+	type Cluster struct {
+		Primary *Config           `yaml:"primary"`
+		Replica *Config           `yaml:"replica"`
+		Labels  map[string]string `yaml:"labels"`
+		Extra   map[string]string `yaml:"extra"`
+		Ports   []int             `yaml:"ports"`
+		Both    []int             `yaml:"both"`
+		Other   *Config           `yaml:"other"`
+	}
+	shared := &Config{Name: "db"}
+	labels := map[string]string{"app": "web"}
+	ports := []int{80, 443}
+	v := Cluster{
+		Primary: shared,
+		Replica: shared,
+		Labels:  labels,
+		Extra:   labels,
+		Ports:   ports,
+		Both:    ports,
+		Other:   &Config{Name: "db"},
+	}
+
+	a := require.New(t)
+
+	data, err := yaml.MarshalWithOptions(v, yaml.EncodeOptions{Anchors: true})
+	a.NoError(err)
+	a.Equal("primary:\n    &id001\n    name: db\nreplica: *id001\n"+
+		"labels:\n    &id002\n    app: web\nextra: *id002\n"+
+		"ports:\n    &id003\n    - 80\n    - 443\nboth: *id003\n"+
+		"other:\n    name: db\n", string(data))
+
+	var decoded Cluster
+	a.NoError(yaml.Unmarshal(data, &decoded))
+	a.Equal(v, decoded)
+
+	// Anchors are disabled by default.
+	data, err = yaml.Marshal(v)
+	a.NoError(err)
+	a.NotContains(string(data), "&")
+
+	// Anchors are scoped to the document.
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.UseAnchors(true)
+	a.NoError(enc.Encode([]*Config{shared, shared}))
+	a.NoError(enc.Encode([]*Config{shared, shared}))
+	a.NoError(enc.Close())
+	a.Equal("-   &id001\n    name: db\n- *id001\n---\n-   &id001\n    name: db\n- *id001\n", buf.String())
+}
+
+type nodeMarshaler struct {
+	Value  string
+	Anchor string
+}
+
+func (m *nodeMarshaler) MarshalYAML() (any, error) {
+	return &yaml.Node{
+		Kind:   yaml.MappingNode,
+		Anchor: m.Anchor,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "k"},
+			{Kind: yaml.ScalarNode, Value: m.Value},
+		},
+	}, nil
+}
+
+func TestEncoderAnchorsMarshaler(t *testing.T) {
+	tests := []struct {
+		value  *nodeMarshaler
+		result string
+	}{
+		{&nodeMarshaler{Value: "x"}, "a:\n    &id001\n    k: x\nb: *id001\n"},
+		{&nodeMarshaler{Value: "x", Anchor: "own"}, "a:\n    &own\n    k: x\nb: *own\n"},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			v := map[string]*nodeMarshaler{"a": tt.value, "b": tt.value}
+			data, err := yaml.MarshalWithOptions(v, yaml.EncodeOptions{Anchors: true})
+			a.NoError(err)
+			a.Equal(tt.result, string(data))
+
+			var decoded map[string]map[string]string
+			a.NoError(yaml.Unmarshal(data, &decoded))
+			a.Equal(map[string]map[string]string{
+				"a": {"k": "x"},
+				"b": {"k": "x"},
+			}, decoded)
+		})
+	}
+}
+
+func TestMarshalCycle(t *testing.T) {
+	type Node struct {
+		Name string `yaml:"name"`
+		Next *Node  `yaml:"next"`
+	}
+	list := &Node{Name: "a"}
+	list.Next = &Node{Name: "b", Next: list}
+
+	m := map[string]any{}
+	m["self"] = m
+
+	s := []any{nil}
+	s[0] = s
+
+	for i, v := range []any{list, m, s} {
+		v := v
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			for _, anchors := range []bool{false, true} {
+				a := require.New(t)
+
+				_, err := yaml.MarshalWithOptions(v, yaml.EncodeOptions{Anchors: anchors})
+				var merr *yaml.MarshalError
+				a.ErrorAs(err, &merr)
+				a.Contains(err.Error(), "encountered a cycle")
+			}
+		})
+	}
+}
+
+func TestMarshalComments(t *testing.T) {
+	a := require.New(t)
+
+	type TLS struct {
+		Enabled bool   `yaml:"enabled" linecomment:"Disabled by default"`
+		Cert    string `yaml:"cert,omitempty,comment=Path to the certificate"`
+	}
+	type Config struct {
+		Host  string   `yaml:"host" comment:"Host to listen on, e.g. localhost"`
+		Port  int      `yaml:"port,linecomment=TCP port"`
+		TLS   TLS      `yaml:"tls" comment:"TLS settings.\nSee docs for details."`
+		Peers []string `yaml:"peers" linecomment:"Static peers"`
+	}
+
+	v := Config{
+		Host:  "localhost",
+		Port:  8080,
+		TLS:   TLS{Cert: "cert.pem"},
+		Peers: []string{"a", "b"},
+	}
+	data, err := yaml.Marshal(v)
+	a.NoError(err)
+	a.Equal("# Host to listen on, e.g. localhost\n"+
+		"host: localhost\n"+
+		"port: 8080 # TCP port\n"+
+		"# TLS settings.\n"+
+		"# See docs for details.\n"+
+		"tls:\n"+
+		"    enabled: false # Disabled by default\n"+
+		"    # Path to the certificate\n"+
+		"    cert: cert.pem\n"+
+		"peers: # Static peers\n"+
+		"    - a\n"+
+		"    - b\n", string(data))
+
+	var decoded Config
+	a.NoError(yaml.Unmarshal(data, &decoded))
+	a.Equal(v, decoded)
+}
+
+func TestMarshalStyleFlags(t *testing.T) {
+	type T struct {
+		Literal      string   `yaml:"literal,literal"`
+		Folded       string   `yaml:"folded,folded"`
+		Quoted       string   `yaml:"quoted,quoted"`
+		SingleQuoted *string  `yaml:"single,singlequoted"`
+		Steps        []string `yaml:"steps,literal"`
+	}
+
+	tests := []struct {
+		value  T
+		expect string
+	}{
+		{
+			T{
+				Literal:      "echo a\necho b\n",
+				Folded:       "a long line",
+				Quoted:       "text",
+				SingleQuoted: ptrTo("it's"),
+			},
+			"literal: |\n    echo a\n    echo b\n" +
+				"folded: >-\n    a long line\n" +
+				"quoted: \"text\"\n" +
+				"single: 'it''s'\n" +
+				"steps: []\n",
+		},
+		{
+			// Fall back to a style which can represent the value.
+			T{
+				Literal:      "trailing space \n",
+				Folded:       "",
+				Quoted:       "a\nb",
+				SingleQuoted: ptrTo("tab\t\n"),
+				Steps:        []string{"a"},
+			},
+			"literal: \"trailing space \\n\"\n" +
+				"folded: \"\"\n" +
+				"quoted: \"a\\nb\"\n" +
+				"single: \"tab\\t\\n\"\n" +
+				"steps:\n    - a\n",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			data, err := yaml.Marshal(tt.value)
+			a.NoError(err)
+			a.Equal(tt.expect, string(data))
+
+			var decoded T
+			a.NoError(yaml.Unmarshal(data, &decoded))
+			a.Equal(tt.value.Literal, decoded.Literal)
+			a.Equal(tt.value.Folded, decoded.Folded)
+			a.Equal(tt.value.Quoted, decoded.Quoted)
+			a.Equal(tt.value.SingleQuoted, decoded.SingleQuoted)
+		})
+	}
+
+	a := require.New(t)
+	a.Panics(func() {
+		_, _ = yaml.Marshal(struct {
+			A string `yaml:"a,literal,quoted"`
+		}{})
+	})
+}
+
+type requestIDKey struct{}
+
+type requestID struct{}
+
+func (requestID) MarshalYAML(ctx context.Context) (any, error) {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	if id == "" {
+		return nil, errors.New("no request id")
+	}
+	return id, nil
+}
+
+func TestEncoderEncodeContext(t *testing.T) {
+	a := require.New(t)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+	a.NoError(enc.EncodeContext(ctx, map[string]any{
+		"id":  requestID{},
+		"ids": []requestID{{}},
+	}))
+	a.NoError(enc.Close())
+	a.Equal("id: abc\nids:\n    - abc\n", buf.String())
+
+	// Encode uses the background context.
+	enc = yaml.NewEncoder(&buf)
+	a.EqualError(enc.Encode(requestID{}), "no request id")
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	enc = yaml.NewEncoder(&buf)
+	a.ErrorIs(enc.EncodeContext(ctx, requestID{}), context.Canceled)
+}
+
+type writeRecorder struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestEncoderStream(t *testing.T) {
+	type record struct {
+		ID   int    `yaml:"id"`
+		Name string `yaml:"name"`
+	}
+	records := make([]record, 1000)
+	for i := range records {
+		records[i] = record{ID: i, Name: fmt.Sprintf("record-%d", i)}
+	}
+	expected, err := yaml.Marshal(map[string]any{
+		"kind":    "export",
+		"records": records,
+	})
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 4096} {
+		size := size
+		t.Run(fmt.Sprintf("Size%d", size), func(t *testing.T) {
+			a := require.New(t)
+
+			var w writeRecorder
+			enc := yaml.NewEncoder(&w)
+			enc.SetBufferSize(size)
+			a.NoError(enc.BeginMapping())
+			a.NoError(enc.EncodeItem("kind"))
+			a.NoError(enc.EncodeItem("export"))
+			a.NoError(enc.EncodeItem("records"))
+			a.NoError(enc.BeginSequence())
+			for _, r := range records {
+				a.NoError(enc.EncodeItem(r))
+			}
+			// Data is written before the sequence is complete.
+			a.NotEmpty(w.writes)
+			a.NoError(enc.EndSequence())
+			a.NoError(enc.EndMapping())
+			a.NoError(enc.Close())
+			a.Equal(string(expected), w.String())
+
+			limit := size
+			if limit == 0 {
+				limit = 128
+			}
+			for _, n := range w.writes {
+				a.LessOrEqual(n, limit)
+			}
+			if size > 0 {
+				a.Greater(w.writes[0], 128)
+			}
+		})
+	}
+
+	t.Run("Documents", func(t *testing.T) {
+		a := require.New(t)
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		a.NoError(enc.BeginSequence())
+		a.NoError(enc.EncodeItem(1))
+		a.NoError(enc.BeginSequence())
+		a.NoError(enc.EncodeItem("a"))
+		a.NoError(enc.EndSequence())
+		a.NoError(enc.EndSequence())
+		a.NoError(enc.Encode(map[string]int{"b": 2}))
+		a.NoError(enc.BeginSequence())
+		a.NoError(enc.EndSequence())
+		a.NoError(enc.Close())
+		a.Equal("- 1\n-   - a\n---\nb: 2\n---\n[]\n", buf.String())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		a := require.New(t)
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		a.EqualError(enc.EncodeItem(1), "yaml: no open sequence or mapping to encode item to")
+		a.EqualError(enc.EndSequence(), "yaml: no open sequence to end")
+
+		a.NoError(enc.BeginMapping())
+		a.EqualError(enc.EndSequence(), "yaml: cannot end sequence, mapping is open")
+		a.NoError(enc.EncodeItem("key"))
+		a.EqualError(enc.EndMapping(), "yaml: mapping key has no value")
+		a.EqualError(enc.Encode(1), "yaml: unterminated mapping")
+		a.EqualError(enc.Close(), "yaml: unterminated mapping")
+	})
+}
+
 func ptrTo[T any](val T) *T {
 	return &val
 }
@@ -0,0 +1,92 @@
+package yaml
+
+import (
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// expandEnv replaces ${VAR}, ${VAR:-default} and ${VAR:?error} references
+// in s using given lookup function.
+//
+// The $${ sequence is replaced by literal ${.
+func expandEnv(s string, lookup func(key string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		if i > 0 && s[i-1] == '$' {
+			// Escaped reference.
+			sb.WriteString(s[:i])
+			sb.WriteByte('{')
+			s = s[i+2:]
+			continue
+		}
+		sb.WriteString(s[:i])
+		s = s[i+2:]
+
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", errors.New("unterminated variable reference")
+		}
+		ref := s[:end]
+		s = s[end+1:]
+
+		v, err := expandRef(ref, lookup)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(v)
+	}
+	sb.WriteString(s)
+	return sb.String(), nil
+}
+
+func expandRef(ref string, lookup func(key string) (string, bool)) (string, error) {
+	name, op, arg := ref, "", ""
+	if i := strings.IndexByte(ref, ':'); i >= 0 {
+		name, op = ref[:i], ref[i:]
+		if len(op) < 2 || (op[1] != '-' && op[1] != '?') {
+			return "", errors.Errorf("invalid variable reference %q", ref)
+		}
+		op, arg = op[:2], op[2:]
+	}
+	if !isEnvName(name) {
+		return "", errors.Errorf("invalid variable name %q", name)
+	}
+
+	v, ok := lookup(name)
+	if ok && v != "" {
+		return v, nil
+	}
+	switch op {
+	case ":-":
+		return arg, nil
+	case ":?":
+		if arg == "" {
+			return "", errors.Errorf("required variable %q is not set", name)
+		}
+		return "", errors.Errorf("required variable %q is not set: %s", name, arg)
+	}
+	return v, nil
+}
+
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
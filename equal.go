@@ -0,0 +1,234 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// EqualOption is an option of Node.Equal.
+type EqualOption int
+
+const (
+	// IgnoreComments makes Node.Equal ignore head, line and foot comments.
+	IgnoreComments EqualOption = 1 << iota
+	// IgnoreStyle makes Node.Equal ignore node styles.
+	IgnoreStyle
+)
+
+// Equal reports whether n and other represent the same YAML node.
+//
+// Nodes are compared as defined by the YAML spec, see
+// https://yaml.org/spec/1.2.2/#node-comparison. Scalars are equal if they
+// have the same resolved tag and canonical value, so 0x10 equals 16 and
+// "\x41" equals A. Unlike the spec, integers and floats are compared as
+// numbers, so 10 equals 1e1. Sequences are compared item by item.
+// Mappings are compared as unordered sets of key-value pairs, with merge
+// keys applied.
+//
+// Aliases are followed: comments and styles of alias nodes are not
+// compared, aliased nodes are compared instead. Anchors and positions
+// are not compared. Comments and styles are compared, unless
+// IgnoreComments or IgnoreStyle is given.
+func (n *Node) Equal(other *Node, opts ...EqualOption) bool {
+	var c nodeComparer
+	for _, o := range opts {
+		c.opts |= o
+	}
+	return c.equal(n, other)
+}
+
+// equalKey reports whether n and other are the same mapping key.
+//
+// Unlike Equal, it requires numbers to have the same resolved tag, since
+// 1 and 1.0 are decoded to different Go values.
+func (n *Node) equalKey(other *Node) bool {
+	c := nodeComparer{
+		opts:       IgnoreComments | IgnoreStyle,
+		strictTags: true,
+	}
+	return c.equal(n, other)
+}
+
+type nodeComparer struct {
+	opts EqualOption
+	// strictTags disables comparison of integers and floats as numbers.
+	strictTags bool
+	// visiting is a set of node pairs being compared, to stop on recursive
+	// aliases.
+	visiting map[[2]*Node]struct{}
+}
+
+func (c *nodeComparer) equal(a, b *Node) bool {
+	switch {
+	case a == b:
+		return true
+	case a == nil || b == nil:
+		return false
+	}
+	a, b = queryDeref(a), queryDeref(b)
+	switch {
+	case a == b:
+		return true
+	case a.Kind != b.Kind:
+		return false
+	case c.opts&IgnoreStyle == 0 && a.Style != b.Style:
+		return false
+	case c.opts&IgnoreComments == 0 && (a.HeadComment != b.HeadComment ||
+		a.LineComment != b.LineComment ||
+		a.FootComment != b.FootComment):
+		return false
+	}
+
+	switch a.Kind {
+	case ScalarNode:
+		return c.equalScalars(a, b)
+	case AliasNode:
+		// Unresolved aliases.
+		return a.Value == b.Value
+	}
+	if a.ShortTag() != b.ShortTag() {
+		return false
+	}
+
+	pair := [2]*Node{a, b}
+	if _, ok := c.visiting[pair]; ok {
+		// Recursive nodes, the difference, if any, is found by the outer call.
+		return true
+	}
+	if c.visiting == nil {
+		c.visiting = map[[2]*Node]struct{}{}
+	}
+	c.visiting[pair] = struct{}{}
+	defer delete(c.visiting, pair)
+
+	if a.Kind == MappingNode {
+		return c.equalMappings(a, b)
+	}
+	if len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !c.equal(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *nodeComparer) equalMappings(a, b *Node) bool {
+	type nodePair struct {
+		Key *Node
+		Val *Node
+	}
+
+	// Group pairs of b by canonical key to avoid comparing every pair
+	// with each other.
+	nodes := map[string][]nodePair{}
+	count := 0
+	queryPairs(b, func(k, v *Node) {
+		key := canonicalKey(k)
+		nodes[key] = append(nodes[key], nodePair{k, v})
+		count++
+	})
+
+	equal := true
+	queryPairs(a, func(k, v *Node) {
+		count--
+		if !equal {
+			return
+		}
+		key := canonicalKey(k)
+		similar := nodes[key]
+		for i, pair := range similar {
+			if c.equal(k, pair.Key) && c.equal(v, pair.Val) {
+				nodes[key] = append(similar[:i], similar[i+1:]...)
+				return
+			}
+		}
+		equal = false
+	})
+	return equal && count == 0
+}
+
+// canonicalKey returns a string which is the same for equal scalar keys.
+func canonicalKey(n *Node) string {
+	n = queryDeref(n)
+	if n.Kind != ScalarNode {
+		return n.Kind.String()
+	}
+	tag, v := canonicalValue(n)
+	switch v := v.(type) {
+	case string:
+		return tag + " " + v
+	case time.Time:
+		return tag + " " + v.UTC().Format(time.RFC3339Nano)
+	}
+	if f, ok := queryNumber(v); ok && isNumberTag(tag) {
+		return "number " + strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%s %v", tag, v)
+}
+
+// canonicalValue returns the resolved tag and value of the scalar node.
+//
+// Values which do not match the tag are returned as is.
+func canonicalValue(n *Node) (string, any) {
+	switch tag := n.ShortTag(); tag {
+	case intTag, floatTag, boolTag, nullTag, timestampTag:
+		rtag, v := resolve("", n.Value)
+		if rtag == tag || tag == floatTag && rtag == intTag {
+			return tag, v
+		}
+		return tag, n.Value
+	case binaryTag:
+		data, err := base64.StdEncoding.DecodeString(n.Value)
+		if err != nil {
+			return tag, n.Value
+		}
+		return tag, string(data)
+	default:
+		return tag, n.Value
+	}
+}
+
+func isNumberTag(tag string) bool {
+	return tag == intTag || tag == floatTag
+}
+
+func (c *nodeComparer) equalScalars(a, b *Node) bool {
+	atag, av := canonicalValue(a)
+	btag, bv := canonicalValue(b)
+	if isNumberTag(atag) && isNumberTag(btag) && (!c.strictTags || atag == btag) {
+		if equal, ok := equalNumbers(av, bv); ok {
+			return equal
+		}
+	}
+	if atag != btag {
+		return false
+	}
+	if at, ok := av.(time.Time); ok {
+		bt, ok := bv.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return av == bv
+}
+
+// equalNumbers compares resolved numbers. It returns false ok, if any of
+// values is not a number.
+func equalNumbers(a, b any) (equal, ok bool) {
+	af, aok := queryNumber(a)
+	bf, bok := queryNumber(b)
+	if !aok || !bok {
+		return false, false
+	}
+	_, afloat := a.(float64)
+	_, bfloat := b.(float64)
+	if !afloat && !bfloat {
+		// float64 can't represent all 64-bit integers.
+		return fmt.Sprint(a) == fmt.Sprint(b), true
+	}
+	return af == bf || math.IsNaN(af) && math.IsNaN(bf), true
+}
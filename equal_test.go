@@ -0,0 +1,234 @@
+package yaml
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNode_Equal(t *testing.T) {
+	mustNode := func(s string) *Node {
+		var n Node
+		require.NoError(t, Unmarshal([]byte(s), &n))
+		if n.Kind == DocumentNode {
+			return n.Content[0]
+		}
+		return &n
+	}
+	theNode := mustNode(`a: 1`)
+
+	tests := []struct {
+		a, b *Node
+		want bool
+	}{
+		// Same node.
+		{theNode, theNode, true},
+
+		// Nil nodes.
+		{nil, nil, true},
+		{nil, mustNode("null"), false},
+		{mustNode("null"), nil, false},
+
+		// Different kinds.
+		{mustNode("{}"), mustNode("[]"), false},
+		{mustNode("{}"), mustNode("true"), false},
+		{mustNode("[]"), mustNode("true"), false},
+		{mustNode("{}: 1"), mustNode("a: 1"), false},
+		{mustNode("{{}: 1}: 1"), mustNode("{a: 1}: 1"), false},
+		{mustNode("{{}: 1, b: 2}: 1"), mustNode("{a: 1, b: 2}: 1"), false},
+
+		// Scalars.
+		{mustNode("null"), mustNode("null"), true},
+		{mustNode("true"), mustNode("true"), true},
+		{mustNode("false"), mustNode("false"), true},
+		{mustNode("0"), mustNode("0"), true},
+		{mustNode("1"), mustNode("1"), true},
+		{mustNode("foo"), mustNode("foo"), true},
+		{mustNode(`"f_\u000a_oo"`), mustNode(`"f_\n_oo"`), true},
+
+		{mustNode("true"), mustNode("false"), false},
+		{mustNode("null"), mustNode("false"), false},
+		{mustNode("1"), mustNode("0"), false},
+		{mustNode("baz"), mustNode("foo"), false},
+
+		// Arrays.
+		{mustNode("[]"), mustNode("[]"), true},
+		{mustNode("[0]"), mustNode("[0]"), true},
+		{mustNode("- 0"), mustNode("[0]"), true},
+		{mustNode("[0, 1]"), mustNode("[0, 1]"), true},
+
+		{mustNode("[0]"), mustNode("[1]"), false},
+		{mustNode("[0, 1]"), mustNode("[0]"), false},
+
+		// Objects.
+		{mustNode("{}"), mustNode("{}"), true},
+		{mustNode("a: 1"), mustNode("a: 1"), true},
+		{mustNode(`{"a": 1}`), mustNode("a: 1"), true},
+		{mustNode("a: 1"), mustNode("a: 1 # comment"), true},
+		{mustNode("a: 1\nb: 1"), mustNode("b: 1\na: 1"), true},
+		{mustNode("a: 1\nc: [{}]\nb: 1"), mustNode("b: 1\nc: [{}]\na: 1"), true},
+
+		{mustNode("a: 1"), mustNode("a: 2"), false},
+		{mustNode("a: 1"), mustNode("b: 1"), false},
+		{mustNode("a: 1\nb: 1"), mustNode("b: 1"), false},
+		{mustNode("a: 1\nb: 1\nc: 1"), mustNode("a: 1\nb: 1\nc: 2"), false},
+
+		// Objects with complex keys.
+		{mustNode("[]: 1"), mustNode("[]: 1"), true},
+		{mustNode("{}: 1"), mustNode("{}: 1"), true},
+		{mustNode("{a: 1}: 1"), mustNode("{a: 1}: 1"), true},
+		{mustNode("{b: 1, a: 1}: 1"), mustNode("{a: 1, b: 1}: 1"), true},
+
+		{mustNode("{a: 1}: 1"), mustNode("{a: 2}: 1"), false},
+		{mustNode("{a: 1, b: 2}: 1"), mustNode("{a: 1}: 1"), false},
+		{mustNode("{b: 1, a: 1}: 1"), mustNode("{a: 1, b: []}: 1"), false},
+
+		// Canonical representation.
+		// !!int
+		{mustNode("10"), mustNode("+10"), true},
+		{mustNode("10"), mustNode("0xa"), true},
+		{mustNode("10"), mustNode("0o12"), true},
+		{mustNode("10"), mustNode("0b1010"), true},
+		{mustNode("0xA"), mustNode("0xa"), true},
+		{mustNode("0x10"), mustNode("16"), true},
+		{mustNode("9223372036854775807"), mustNode("9223372036854775806"), false},
+		// !!float
+		{mustNode("10"), mustNode("10.0"), true},
+		{mustNode("10"), mustNode("1e1"), true},
+		{mustNode("!!float 10"), mustNode("1e1"), true},
+		{mustNode(".nan"), mustNode(".NaN"), true},
+		{mustNode(".inf"), mustNode("+.inf"), true},
+		{mustNode("10"), mustNode("10.5"), false},
+		// !!bool, !!null
+		{mustNode("true"), mustNode("True"), true},
+		{mustNode("null"), mustNode("~"), true},
+		{mustNode("{a: null}"), mustNode("{a: }"), true},
+		// !!str
+		{mustNode("10"), mustNode(`"10"`), false},
+		{mustNode("true"), mustNode(`'true'`), false},
+		{mustNode("foo"), mustNode(`"foo"`), true},
+		{mustNode("foo"), mustNode("!!str foo"), true},
+		{mustNode(`"\x41"`), mustNode("A"), true},
+		// !!binary
+		{mustNode("!!binary Zm9v"), mustNode("!!binary Zm9v"), true},
+		{mustNode("!!binary Zm9v"), mustNode("foo"), false},
+		// !!timestamp
+		{mustNode("2001-12-14t21:59:43.10-05:00"), mustNode("2001-12-15T02:59:43.1Z"), true},
+		{mustNode("2001-12-14"), mustNode(`"2001-12-14"`), false},
+		// Custom tags.
+		{mustNode("!foo 10"), mustNode("!foo 10"), true},
+		{mustNode("!foo 10"), mustNode("!bar 10"), false},
+		{mustNode("!foo 10"), mustNode("10"), false},
+
+		// Aliases.
+		{mustNode("&a 1"), mustNode("1"), true},
+		{mustNode("[&a {b: 1}, *a]"), mustNode("[{b: 1}, {b: 1}]"), true},
+		{mustNode("[&a {b: 1}, *a]"), mustNode("[{b: 1}, {b: 2}]"), false},
+		{mustNode("{&a a: 1, *a : 2}"), mustNode("{a: 1, a: 2}"), true},
+
+		// Merge keys.
+		{mustNode("{a: &a {x: 1}, b: {<<: *a}}"), mustNode("{a: {x: 1}, b: {x: 1}}"), true},
+		{mustNode("{a: &a {x: 1}, b: {<<: *a, x: 2}}"), mustNode("{a: {x: 1}, b: {x: 2}}"), true},
+		{mustNode("{a: &a {x: 1}, b: {<<: [*a, {y: 2}]}}"), mustNode("{a: {x: 1}, b: {y: 2, x: 1}}"), true},
+		{mustNode("{a: &a {x: 1}, b: {<<: *a}}"), mustNode("{a: {x: 1}, b: {x: 2}}"), false},
+		{mustNode("{a: &a {x: 1}, b: {<<: *a}}"), mustNode("{a: {x: 1}, b: {}}"), false},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			check := a.False
+			if tt.want {
+				check = a.True
+			}
+			// Ensure that equality is symmetric relation.
+			check(tt.a.Equal(tt.b, IgnoreComments, IgnoreStyle))
+			check(tt.b.Equal(tt.a, IgnoreComments, IgnoreStyle))
+		})
+	}
+}
+
+func TestNode_EqualOptions(t *testing.T) {
+	mustNode := func(s string) *Node {
+		var n Node
+		require.NoError(t, Unmarshal([]byte(s), &n))
+		return &n
+	}
+	const input = "# Config.\na: 1 # one\nb: [x, y]\n"
+
+	tests := []struct {
+		a, b string
+		opts []EqualOption
+		want bool
+	}{
+		{input, input, nil, true},
+		{input, "# Config.\na: 0x1 # one\nb: [x, y]\n", nil, true},
+		{input, "a: 1\nb: [x, y]\n", nil, false},
+		{input, "a: 1\nb: [x, y]\n", []EqualOption{IgnoreComments}, true},
+		{input, "# Config.\na: 1 # one\nb:\n    - x\n    - y\n", nil, false},
+		{input, "# Config.\na: 1 # one\nb:\n    - x\n    - y\n", []EqualOption{IgnoreStyle}, true},
+		{input, "# Config.\na: 1 # one\nb: [x, 'y']\n", []EqualOption{IgnoreStyle}, true},
+		{input, "a: 1\nb: ['x', y]\n", []EqualOption{IgnoreComments, IgnoreStyle}, true},
+		{input, "a: 1\nb: [x, z]\n", []EqualOption{IgnoreComments, IgnoreStyle}, false},
+		// Comments and styles of aliases are ignored.
+		{"a: &a 1 # one\nb: *a # alias\n", "a: 1 # one\nb: 1 # one\n", nil, true},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			n, other := mustNode(tt.a), mustNode(tt.b)
+			a.Equal(tt.want, n.Equal(other, tt.opts...))
+			a.Equal(tt.want, other.Equal(n, tt.opts...))
+		})
+	}
+
+	t.Run("Recursive", func(t *testing.T) {
+		a := require.New(t)
+
+		recursive := func(value string) *Node {
+			n := &Node{Kind: SequenceNode, Anchor: "a"}
+			n.Content = []*Node{
+				{Kind: AliasNode, Value: "a", Alias: n},
+				{Kind: ScalarNode, Value: value},
+			}
+			return n
+		}
+		a.True(recursive("1").Equal(recursive("0x1")))
+		a.False(recursive("1").Equal(recursive("2")))
+	})
+}
+
+func TestNode_equalKey(t *testing.T) {
+	mustNode := func(s string) *Node {
+		var n Node
+		require.NoError(t, Unmarshal([]byte(s), &n))
+		return n.Content[0]
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"0x10", "16", true},
+		{"a # comment", "'a'", true},
+		{"1", "1.0", false},
+		{"10", "1e1", false},
+		{"!!float 1", "1.0", true},
+		{"{a: 1}", "{a: 0x1}", true},
+		{"{a: 1}", "{a: 1.0}", false},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			n, other := mustNode(tt.a), mustNode(tt.b)
+			a.Equal(tt.want, n.equalKey(other))
+			a.Equal(tt.want, other.equalKey(n))
+		})
+	}
+}
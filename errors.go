@@ -12,14 +12,39 @@ var _ = []interface {
 }{
 	(*SyntaxError)(nil),
 	(*UnmarshalError)(nil),
+	(*QueryError)(nil),
 }
 
+var (
+	// ErrMaxDepth is returned when the nesting depth of the document
+	// exceeds the limit.
+	ErrMaxDepth = errors.New("exceeded max depth")
+	// ErrMaxAliases is returned when the number of values decoded
+	// through alias expansion exceeds the limit.
+	ErrMaxAliases = errors.New("document contains excessive aliasing")
+	// ErrMaxBytes is returned when the size of the input exceeds the limit.
+	ErrMaxBytes = errors.New("exceeded max document size")
+	// ErrMaxScalarLength is returned when the length of a scalar exceeds
+	// the limit.
+	ErrMaxScalarLength = errors.New("exceeded max scalar length")
+	// ErrMaxNodes is returned when the number of nodes in a document
+	// exceeds the limit.
+	ErrMaxNodes = errors.New("exceeded max node count")
+)
+
 // SyntaxError is an error that occurs during parsing.
 type SyntaxError struct {
+	// File is the name of the file, if known. For example, the name of
+	// an included file.
+	File   string
 	Offset int
 	Line   int
 	Column int
 	Msg    string
+	// Err is the underlying error, if any.
+	//
+	// For example, ErrMaxDepth if the document is nested too deeply.
+	Err error
 }
 
 func syntaxErr(offset, line, column int, msg string) error {
@@ -40,24 +65,35 @@ func syntaxErrf(offset, line, column int, msgf string, args ...any) error {
 	}
 }
 
+// Unwrap returns the underlying error.
+func (s *SyntaxError) Unwrap() error {
+	return s.Err
+}
+
 // Error returns the error message.
 func (s *SyntaxError) Error() string {
-	if s.Line == 0 {
-		if s.Offset == 0 {
-			return fmt.Sprintf("yaml: %s", s.Msg)
-		}
-		return fmt.Sprintf("yaml: offset %d: %s", s.Offset, s.Msg)
+	var pos string
+	switch {
+	case s.Line == 0 && s.Offset == 0:
+	case s.Line == 0:
+		pos = fmt.Sprintf("offset %d: ", s.Offset)
+	case s.Column == 0:
+		pos = fmt.Sprintf("line %d: ", s.Line)
+	default:
+		pos = fmt.Sprintf("line %d:%d: ", s.Line, s.Column)
 	}
-	if s.Column == 0 {
-		return fmt.Sprintf("yaml: line %d: %s", s.Line, s.Msg)
+	if s.File != "" {
+		pos = s.File + ": " + pos
 	}
-	return fmt.Sprintf("yaml: line %d:%d: %s", s.Line, s.Column, s.Msg)
+	return "yaml: " + pos + s.Msg
 }
 
 // UnknownFieldError reports an unknown field.
 type UnknownFieldError struct {
 	Field string
 	Type  reflect.Type
+	// Path is the path of the field in the document.
+	Path Path
 }
 
 // Error returns the error message.
@@ -65,24 +101,50 @@ func (d *UnknownFieldError) Error() string {
 	return fmt.Sprintf("field %q not found in type %s", d.Field, d.Type)
 }
 
-func unknownFieldErr(field string, f *Node, typ reflect.Type) error {
+// MissingFieldError reports a required field missing in a mapping.
+type MissingFieldError struct {
+	Field string
+	Type  reflect.Type
+	// Path is the path of the field in the document.
+	Path Path
+}
+
+// Error returns the error message.
+func (d *MissingFieldError) Error() string {
+	return fmt.Sprintf("required field %q is missing in type %s", d.Field, d.Type)
+}
+
+func missingFieldErr(field string, n *Node, typ reflect.Type, path Path) error {
+	return &UnmarshalError{
+		Node: n,
+		Type: typ,
+		Path: path,
+		Err:  &MissingFieldError{Field: field, Type: typ, Path: path},
+	}
+}
+
+func unknownFieldErr(field string, f *Node, typ reflect.Type, path Path) error {
 	return &UnmarshalError{
 		Node: f,
 		Type: typ,
-		Err:  &UnknownFieldError{Field: field, Type: typ},
+		Path: path,
+		Err:  &UnknownFieldError{Field: field, Type: typ, Path: path},
 	}
 }
 
 // DuplicateKeyError reports a duplicate key.
 type DuplicateKeyError struct {
 	First, Second *Node
+	// Path is the path of the key in the document.
+	Path Path
 }
 
-func duplicateKeyErr(f, s *Node, typ reflect.Type) error {
+func duplicateKeyErr(f, s *Node, typ reflect.Type, path Path) error {
 	return &UnmarshalError{
 		Node: f,
 		Type: typ,
-		Err:  &DuplicateKeyError{First: f, Second: s},
+		Path: path,
+		Err:  &DuplicateKeyError{First: f, Second: s, Path: path},
 	}
 }
 
@@ -104,6 +166,10 @@ func (d *DuplicateKeyError) Error() string {
 type UnmarshalError struct {
 	Node *Node
 	Type reflect.Type
+	// Path is the path of the node in the document, like
+	// spec.containers[2].ports[0]. It is empty for the root node
+	// and for errors not bound to a decoded value.
+	Path Path
 	Err  error
 }
 
@@ -123,10 +189,14 @@ func (s *UnmarshalError) Unwrap() error {
 // Error returns the error message.
 func (s *UnmarshalError) Error() string {
 	n := s.Node
-	if n == nil || n.Line == 0 {
+	switch {
+	case n == nil || n.Line == 0:
 		return fmt.Sprintf("yaml: %s", s.Err)
+	case n.File != "":
+		return fmt.Sprintf("yaml: %s: line %d: %s", n.File, n.Line, s.Err)
+	default:
+		return fmt.Sprintf("yaml: line %d: %s", n.Line, s.Err)
 	}
-	return fmt.Sprintf("yaml: line %d: %s", n.Line, s.Err)
 }
 
 // MarshalError is an error that occurs during marshaling.
@@ -139,6 +209,18 @@ func (s *MarshalError) Error() string {
 	return fmt.Sprintf("yaml: %s", s.Msg)
 }
 
+// QueryError reports an invalid Node.Query expression.
+type QueryError struct {
+	Query  string
+	Offset int
+	Msg    string
+}
+
+// Error returns the error message.
+func (s *QueryError) Error() string {
+	return fmt.Sprintf("yaml: query %q: offset %d: %s", s.Query, s.Offset, s.Msg)
+}
+
 // A TypeError is returned by Unmarshal when one or more fields in
 // the YAML document cannot be properly decoded into the requested
 // types. When this error is returned, the value is still
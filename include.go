@@ -0,0 +1,144 @@
+package yaml
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+const includeTag = "!include"
+
+// NewDecoderFS returns a new decoder that reads the named file from fsys.
+//
+// Unlike NewDecoder, the returned decoder resolves nodes tagged with
+// !include by replacing them with the first document of the referenced
+// file. Paths are relative to the including file, for example:
+//
+//	database: !include db/config.yaml
+//
+// Include cycles are reported as errors. Decoded nodes record the name of
+// the file they came from in the File field, so errors point to the
+// right file and line.
+//
+// The MaxBytes limit applies to the total size of all read files, and
+// the MaxNodes limit applies to the document with all includes resolved.
+func NewDecoderFS(fsys fs.FS, name string) (*Decoder, error) {
+	name = path.Clean(name)
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{
+		parser: newParser(data),
+		include: &includer{
+			fsys:  fsys,
+			name:  name,
+			bytes: len(data),
+		},
+	}, nil
+}
+
+// includer resolves !include nodes.
+type includer struct {
+	fsys   fs.FS
+	name   string
+	limits Limits
+	stack  []string
+	// bytes is the total size of read files.
+	bytes int
+	// nodes is the number of nodes of the document being resolved.
+	nodes int
+}
+
+// resolve resolves includes in the given node parsed from the root file.
+func (inc *includer) resolve(n *Node, limits Limits) {
+	inc.limits = limits
+	inc.stack = append(inc.stack[:0], inc.name)
+	inc.nodes = 0
+	inc.walk(n)
+}
+
+func (inc *includer) walk(n *Node) {
+	n.File = inc.stack[len(inc.stack)-1]
+	if shortTag(n.Tag) == includeTag {
+		inc.include(n)
+		return
+	}
+	inc.nodes++
+	if max := inc.limits.MaxNodes; max > 0 && inc.nodes > max {
+		fail(&UnmarshalError{
+			Node: n,
+			Err:  errors.Wrapf(ErrMaxNodes, "limit of %d nodes", max),
+		})
+	}
+	for _, c := range n.Content {
+		inc.walk(c)
+	}
+}
+
+func (inc *includer) include(n *Node) {
+	if n.Kind != ScalarNode || n.Value == "" {
+		fail(unmarshalErrf(n, nil, "%s requires a file path", includeTag))
+	}
+
+	name := n.Value
+	if !path.IsAbs(name) {
+		name = path.Join(path.Dir(n.File), name)
+	}
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	for _, f := range inc.stack {
+		if f == name {
+			cycle := strings.Join(append(inc.stack, name), " -> ")
+			fail(unmarshalErrf(n, nil, "include cycle: %s", cycle))
+		}
+	}
+
+	doc, err := inc.parse(name)
+	if err != nil {
+		fail(&UnmarshalError{
+			Node: n,
+			Err:  errors.Wrapf(err, "include %q", n.Value),
+		})
+	}
+
+	root := &Node{Kind: ScalarNode, Tag: nullTag}
+	if doc != nil && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	inc.stack = append(inc.stack, name)
+	inc.walk(root)
+	inc.stack = inc.stack[:len(inc.stack)-1]
+
+	// Keep the anchor and comments of the !include node, aliases may
+	// refer to it.
+	include := *n
+	*n = *root
+	n.Anchor = include.Anchor
+	mergeComments(n, root, &include)
+}
+
+// parse parses the first document of the named file.
+func (inc *includer) parse(name string) (doc *Node, err error) {
+	data, err := fs.ReadFile(inc.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	inc.bytes += len(data)
+	if max := inc.limits.MaxBytes; max > 0 && inc.bytes > max {
+		return nil, errors.Wrapf(ErrMaxBytes, "limit of %d bytes", max)
+	}
+	defer func() {
+		// Positions of syntax errors are in the included file.
+		var serr *SyntaxError
+		if errors.As(err, &serr) && serr.File == "" {
+			serr.File = name
+		}
+	}()
+	defer handleErr(&err)
+	p := newParser(data)
+	defer p.destroy()
+	p.setLimits(inc.limits)
+	return p.parse(), nil
+}
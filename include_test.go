@@ -0,0 +1,197 @@
+package yaml_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-faster/yaml"
+)
+
+func TestNewDecoderFS(t *testing.T) {
+	a := require.New(t)
+
+	fsys := fstest.MapFS{
+		"main.yaml":       {Data: []byte("name: app\ndb: !include conf/db.yaml\nempty: !include conf/empty.yaml\n")},
+		"conf/db.yaml":    {Data: []byte("host: localhost\nport: !include port.yaml\n")},
+		"conf/port.yaml":  {Data: []byte("5432\n")},
+		"conf/empty.yaml": {Data: []byte("")},
+	}
+
+	type DB struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	var v struct {
+		Name  string `yaml:"name"`
+		DB    DB     `yaml:"db"`
+		Empty *DB    `yaml:"empty"`
+	}
+	d, err := yaml.NewDecoderFS(fsys, "main.yaml")
+	a.NoError(err)
+	a.NoError(d.Decode(&v))
+	a.Equal("app", v.Name)
+	a.Equal(DB{Host: "localhost", Port: 5432}, v.DB)
+	a.Nil(v.Empty)
+
+	var n yaml.Node
+	d, err = yaml.NewDecoderFS(fsys, "main.yaml")
+	a.NoError(err)
+	a.NoError(d.Decode(&n))
+	root := n.Content[0]
+	a.Equal("main.yaml", root.File)
+	db := root.Content[3]
+	a.Equal(yaml.MappingNode, db.Kind)
+	a.Equal("conf/db.yaml", db.File)
+	a.Equal("conf/db.yaml", db.Content[0].File)
+	a.Equal("conf/port.yaml", db.Content[3].File)
+
+	_, err = yaml.NewDecoderFS(fsys, "missing.yaml")
+	a.Error(err)
+}
+
+func TestNewDecoderFSAnchor(t *testing.T) {
+	a := require.New(t)
+
+	fsys := fstest.MapFS{
+		"main.yaml": {Data: []byte("# Database.\ndb: &db !include db.yaml # shared\nreplica: *db\n")},
+		"db.yaml":   {Data: []byte("# Local database.\nhost: localhost\n")},
+	}
+	d, err := yaml.NewDecoderFS(fsys, "main.yaml")
+	a.NoError(err)
+	var n yaml.Node
+	a.NoError(d.Decode(&n))
+	db := n.Content[0].Content[1]
+	a.Equal("db", db.Anchor)
+	a.Equal("# shared", db.LineComment)
+	a.Same(db, n.Content[0].Content[3].Alias)
+
+	data, err := yaml.Marshal(&n)
+	a.NoError(err)
+	a.Equal("# Database.\ndb:\n    &db\n    # Local database.\n    host: localhost\nreplica: *db\n", string(data))
+
+	var v map[string]map[string]string
+	a.NoError(yaml.Unmarshal(data, &v))
+	a.Equal(map[string]map[string]string{
+		"db":      {"host": "localhost"},
+		"replica": {"host": "localhost"},
+	}, v)
+}
+
+func TestNewDecoderFSErrors(t *testing.T) {
+	tests := []struct {
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			fstest.MapFS{
+				"a.yaml": {Data: []byte("b: !include b.yaml\n")},
+				"b.yaml": {Data: []byte("a: !include a.yaml\n")},
+			},
+			"yaml: b.yaml: line 1: include cycle: a.yaml -> b.yaml -> a.yaml",
+		},
+		{
+			fstest.MapFS{
+				"a.yaml": {Data: []byte("self: !include ./a.yaml\n")},
+			},
+			"yaml: a.yaml: line 1: include cycle: a.yaml -> a.yaml",
+		},
+		{
+			fstest.MapFS{
+				"a.yaml": {Data: []byte("b:\n  c: !include c.yaml\n")},
+			},
+			`yaml: a.yaml: line 2: include "c.yaml": open c.yaml: file does not exist`,
+		},
+		{
+			fstest.MapFS{
+				"a.yaml": {Data: []byte("b: !include b.yaml\n")},
+				"b.yaml": {Data: []byte("a: [\n")},
+			},
+			`yaml: a.yaml: line 1: include "b.yaml": yaml: b.yaml: line 1: did not find expected node content`,
+		},
+		{
+			fstest.MapFS{
+				"a.yaml": {Data: []byte("b: !include [b.yaml]\n")},
+			},
+			"yaml: a.yaml: line 1: !include requires a file path",
+		},
+		{
+			fstest.MapFS{
+				"a.yaml": {Data: []byte("name: a\nb: !include b.yaml\n")},
+				"b.yaml": {Data: []byte("x: 1\ny: foo\n")},
+			},
+			"yaml: unmarshal errors:\n  yaml: b.yaml: line 2: cannot unmarshal !!str `foo` into int",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			d, err := yaml.NewDecoderFS(tt.files, "a.yaml")
+			a.NoError(err)
+			var v struct {
+				Name string         `yaml:"name"`
+				B    map[string]int `yaml:"b"`
+			}
+			a.EqualError(d.Decode(&v), tt.err)
+		})
+	}
+
+	t.Run("SyntaxErrorFile", func(t *testing.T) {
+		a := require.New(t)
+
+		d, err := yaml.NewDecoderFS(fstest.MapFS{
+			"a.yaml": {Data: []byte("b: !include b.yaml\n")},
+			"b.yaml": {Data: []byte("x: 1\ny: [\n")},
+		}, "a.yaml")
+		a.NoError(err)
+		var v any
+		err = d.Decode(&v)
+		var serr *yaml.SyntaxError
+		a.ErrorAs(err, &serr)
+		a.Equal("b.yaml", serr.File)
+		a.Equal(2, serr.Line)
+	})
+}
+
+func TestNewDecoderFSLimits(t *testing.T) {
+	// Every level includes the next one four times.
+	fsys := fstest.MapFS{
+		"a.yaml": {Data: []byte("[!include b.yaml, !include b.yaml, !include b.yaml, !include b.yaml]\n")},
+		"b.yaml": {Data: []byte("[!include c.yaml, !include c.yaml, !include c.yaml, !include c.yaml]\n")},
+		"c.yaml": {Data: []byte("[1, 2, 3, 4]\n")},
+	}
+	tests := []struct {
+		limits yaml.Limits
+		err    error
+		msg    string
+	}{
+		{yaml.Limits{}, nil, ""},
+		{yaml.Limits{MaxNodes: 100}, nil, ""},
+		{yaml.Limits{MaxNodes: 50}, yaml.ErrMaxNodes, "yaml: c.yaml: line 1: limit of 50 nodes: exceeded max node count"},
+		{yaml.Limits{MaxBytes: 1000}, nil, ""},
+		{yaml.Limits{MaxBytes: 500}, yaml.ErrMaxBytes, `yaml: a.yaml: line 1: include "b.yaml": limit of 500 bytes: exceeded max document size`},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			d, err := yaml.NewDecoderFS(fsys, "a.yaml")
+			a.NoError(err)
+			d.SetLimits(tt.limits)
+			var v [][][]int
+			err = d.Decode(&v)
+			if tt.err == nil {
+				a.NoError(err)
+				a.Len(v, 4)
+				return
+			}
+			a.ErrorIs(err, tt.err)
+			a.EqualError(err, tt.msg)
+		})
+	}
+}
@@ -126,3 +126,95 @@ func writeJSON(e *jx.Encoder, n *Node) (rerr error) {
 func (n *Node) EncodeJSON(e *jx.Encoder) error {
 	return writeJSON(e, n)
 }
+
+func readJSONString(n *Node, s string) {
+	n.SetString(s)
+	if n.Style == 0 && n.Tag == strTag {
+		// Quote strings, which would be resolved to another type otherwise.
+		rtag, _ := resolve("", s)
+		if rtag != strTag || isBase60Float(s) || isOldBool(s) {
+			n.Style = DoubleQuotedStyle
+		}
+	}
+}
+
+func readJSON(d *jx.Decoder, n *Node) error {
+	switch tt := d.Next(); tt {
+	case jx.String:
+		s, err := d.Str()
+		if err != nil {
+			return err
+		}
+		readJSONString(n, s)
+		return nil
+	case jx.Number:
+		num, err := d.Num()
+		if err != nil {
+			return err
+		}
+		// Keep the exact textual form of the number.
+		value := num.String()
+		tag, _ := resolve("", value)
+		if tag != intTag {
+			tag = floatTag
+		}
+		*n = Node{Kind: ScalarNode, Tag: tag, Value: value}
+		return nil
+	case jx.Bool:
+		v, err := d.Bool()
+		if err != nil {
+			return err
+		}
+		value := "false"
+		if v {
+			value = "true"
+		}
+		*n = Node{Kind: ScalarNode, Tag: boolTag, Value: value}
+		return nil
+	case jx.Null:
+		if err := d.Null(); err != nil {
+			return err
+		}
+		*n = Node{Kind: ScalarNode, Tag: nullTag, Value: "null"}
+		return nil
+	case jx.Array:
+		*n = Node{Kind: SequenceNode, Tag: seqTag}
+		return d.Arr(func(d *jx.Decoder) error {
+			elem := new(Node)
+			if err := readJSON(d, elem); err != nil {
+				return err
+			}
+			n.Content = append(n.Content, elem)
+			return nil
+		})
+	case jx.Object:
+		*n = Node{Kind: MappingNode, Tag: mapTag}
+		return d.ObjBytes(func(d *jx.Decoder, key []byte) error {
+			k := new(Node)
+			readJSONString(k, string(key))
+			v := new(Node)
+			if err := readJSON(d, v); err != nil {
+				return err
+			}
+			n.Content = append(n.Content, k, v)
+			return nil
+		})
+	default:
+		return errors.Errorf("unexpected JSON type %s", tt)
+	}
+}
+
+// DecodeJSON reads a JSON value from given decoder and stores it
+// into the node.
+//
+// Numbers keep their textual representation, strings are quoted if
+// they would be resolved to another type, and objects preserve
+// the order of keys.
+func (n *Node) DecodeJSON(d *jx.Decoder) error {
+	var result Node
+	if err := readJSON(d, &result); err != nil {
+		return err
+	}
+	*n = result
+	return nil
+}
@@ -124,3 +124,74 @@ func TestNode_EncodeJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestNode_DecodeJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		yaml    string
+		wantErr bool
+	}{
+		{`"foobar"`, "foobar\n", false},
+		{`"10"`, "\"10\"\n", false},
+		{`"true"`, "\"true\"\n", false},
+		{`"yes"`, "\"yes\"\n", false},
+		{`"null"`, "\"null\"\n", false},
+		{`""`, "\"\"\n", false},
+		{`"foo\nbar"`, "|-\n    foo\n    bar\n", false},
+		{`10`, "10\n", false},
+		{`1e1`, "1e1\n", false},
+		{`-0.10`, "-0.10\n", false},
+		{`123456789012345678901234567890`, "123456789012345678901234567890\n", false},
+		{`1E308`, "1E308\n", false},
+		{`true`, "true\n", false},
+		{`null`, "null\n", false},
+		{`[]`, "[]\n", false},
+		{`{}`, "{}\n", false},
+		{`[1, "2", [3]]`, "- 1\n- \"2\"\n-   - 3\n", false},
+		{
+			`{"kind": "Pod", "apiVersion": "v1", "10": 10, "spec": {"b": true, "a": null}}`,
+			"kind: Pod\napiVersion: v1\n\"10\": 10\nspec:\n    b: true\n    a: null\n",
+			false,
+		},
+
+		{``, "", true},
+		{`{`, "", true},
+		{`[1,`, "", true},
+		{`{"a": }`, "", true},
+		{`tru`, "", true},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var n yaml.Node
+			err := n.DecodeJSON(jx.DecodeStr(tt.input))
+			if tt.wantErr {
+				a.Error(err)
+				return
+			}
+			a.NoError(err)
+
+			data, err := yaml.Marshal(&n)
+			a.NoError(err)
+			a.Equal(tt.yaml, string(data))
+
+			// Ensure that JSON round-trips.
+			var e jx.Encoder
+			a.NoError(n.EncodeJSON(&e))
+			a.JSONEq(tt.input, e.String())
+		})
+	}
+
+	t.Run("OutOfRange", func(t *testing.T) {
+		a := require.New(t)
+
+		// Numbers keep the exact text, even if float64 can't represent them.
+		var n yaml.Node
+		a.NoError(n.DecodeJSON(jx.DecodeStr(`{"d": 1E400}`)))
+		d := n.Content[1]
+		a.Equal("1E400", d.Value)
+		a.Equal("!!float", d.Tag)
+	})
+}
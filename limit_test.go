@@ -70,6 +70,130 @@ func TestLimits(t *testing.T) {
 	}
 }
 
+var customLimitTests = []struct {
+	name   string
+	data   string
+	limits yaml.Limits
+	err    error
+	error  string
+}{
+	{
+		name:   "flow depth",
+		data:   "[[[[1]]]]",
+		limits: yaml.Limits{MaxDepth: 3},
+		err:    yaml.ErrMaxDepth,
+		error:  "yaml: offset 3: exceeded max depth of 3",
+	},
+	{
+		name:   "indent depth",
+		data:   "a:\n  b:\n    c:\n      d: 1\n",
+		limits: yaml.Limits{MaxDepth: 3},
+		err:    yaml.ErrMaxDepth,
+		error:  "yaml: line 4:.* exceeded max depth of 3",
+	},
+	{
+		name:   "depth within limit",
+		data:   "[[[1]]]",
+		limits: yaml.Limits{MaxDepth: 3},
+	},
+	{
+		name:   "aliases",
+		data:   "a: &a [1, 2]\nb: [*a, *a]\n",
+		limits: yaml.Limits{MaxAliases: 5},
+		err:    yaml.ErrMaxAliases,
+		error:  "yaml: line 1: document contains excessive aliasing",
+	},
+	{
+		name:   "aliases within limit",
+		data:   "a: &a [1, 2]\nb: [*a, *a]\n",
+		limits: yaml.Limits{MaxAliases: 6},
+	},
+	{
+		name:   "bytes",
+		data:   "a: " + strings.Repeat("b", 100),
+		limits: yaml.Limits{MaxBytes: 64},
+		err:    yaml.ErrMaxBytes,
+		error:  "yaml: offset 64: exceeded max document size of 64 bytes",
+	},
+	{
+		name:   "bytes within limit",
+		data:   "a: " + strings.Repeat("b", 61),
+		limits: yaml.Limits{MaxBytes: 64},
+	},
+	{
+		name:   "scalar length",
+		data:   "a: [foo, foobar]",
+		limits: yaml.Limits{MaxScalarLength: 5},
+		err:    yaml.ErrMaxScalarLength,
+		error:  "yaml: line 1:10: exceeded max scalar length of 5",
+	},
+	{
+		name:   "scalar length of key",
+		data:   "foobar: 1",
+		limits: yaml.Limits{MaxScalarLength: 5},
+		err:    yaml.ErrMaxScalarLength,
+		error:  "yaml: line 1:1: exceeded max scalar length of 5",
+	},
+	{
+		name:   "nodes",
+		data:   "a: [1, 2, 3]",
+		limits: yaml.Limits{MaxNodes: 6},
+		err:    yaml.ErrMaxNodes,
+		error:  "yaml: line 1:11: exceeded max node count of 6",
+	},
+	{
+		name:   "nodes within limit",
+		data:   "a: [1, 2, 3]",
+		limits: yaml.Limits{MaxNodes: 7},
+	},
+	{
+		name:  "default depth",
+		data:  strings.Repeat("[", 10001),
+		err:   yaml.ErrMaxDepth,
+		error: "yaml: offset 10000: exceeded max depth of 10000",
+	},
+}
+
+func TestUnmarshalWithLimits(t *testing.T) {
+	for i, tc := range customLimitTests {
+		tc := tc
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			t.Logf("Test %s", tc.name)
+
+			check := func(a *require.Assertions, err error) {
+				if tc.err == nil {
+					a.NoError(err)
+					return
+				}
+				a.ErrorIs(err, tc.err)
+				a.Regexp(tc.error, err.Error())
+			}
+
+			t.Run("Unmarshal", func(t *testing.T) {
+				var v any
+				check(require.New(t), yaml.UnmarshalWithLimits([]byte(tc.data), &v, tc.limits))
+			})
+			t.Run("Decoder", func(t *testing.T) {
+				var v any
+				d := yaml.NewDecoder(strings.NewReader(tc.data))
+				d.SetLimits(tc.limits)
+				check(require.New(t), d.Decode(&v))
+			})
+		})
+	}
+}
+
+func TestDecoderNodeLimitPerDocument(t *testing.T) {
+	a := require.New(t)
+
+	d := yaml.NewDecoder(strings.NewReader("a: [1, 2]\n---\nb: [3, 4]\n"))
+	d.SetLimits(yaml.Limits{MaxNodes: 6})
+	for i := 0; i < 2; i++ {
+		var v any
+		a.NoError(d.Decode(&v))
+	}
+}
+
 func Benchmark1000KB100Aliases(b *testing.B) {
 	benchmark(b, "1000kb of maps with 100 aliases")
 }
@@ -0,0 +1,29 @@
+package yaml
+
+import "reflect"
+
+// MapItem is an item of MapSlice.
+type MapItem struct {
+	Key, Value any
+}
+
+// MapSlice is a mapping which preserves the order of keys.
+//
+// MapSlice is decoded from a mapping when it is the target type, or
+// when decoding into an interface value with ordered maps enabled
+// (see Decoder.UseOrderedMaps). Nested mappings decoded into interface
+// values of MapSlice are MapSlice as well. It is encoded as a mapping in
+// the order of items.
+type MapSlice []MapItem
+
+var mapSliceType = reflect.TypeOf(MapSlice{})
+
+// Get returns the value of the first item with given key.
+func (s MapSlice) Get(key any) (any, bool) {
+	for _, item := range s {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
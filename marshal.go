@@ -1,6 +1,8 @@
 package yaml
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"reflect"
 )
@@ -15,6 +17,12 @@ type Marshaler interface {
 	MarshalYAML() (any, error)
 }
 
+// MarshalerContext is like Marshaler, but also receives the context
+// passed to Encoder.EncodeContext.
+type MarshalerContext interface {
+	MarshalYAML(ctx context.Context) (any, error)
+}
+
 // Marshal serializes the value provided into a YAML document. The structure
 // of the generated document will reflect the structure of the value itself.
 // Maps and pointers (to struct, string, int, etc) are accepted as the in value.
@@ -42,11 +50,43 @@ type Marshaler interface {
 //	flow         Marshal using a flow style (useful for structs,
 //	             sequences and maps).
 //
+//	literal, folded, quoted, singlequoted
+//	             Marshal a string using the literal (|), folded (>),
+//	             double-quoted or single-quoted style. If the style
+//	             can't represent the value, e.g. a literal string with
+//	             trailing spaces, another style is used.
+//
 //	inline       Inline the field, which must be a struct or a map,
 //	             causing all of its fields or keys to be processed as if
 //	             they were part of the outer struct. For maps, keys must
 //	             not conflict with the yaml keys of other struct fields.
 //
+//	string       Marshal a numeric or boolean field as a double-quoted
+//	             string, and accept such strings when unmarshaling.
+//
+//	required     Report a *MissingFieldError when unmarshaling a
+//	             mapping without the field key.
+//
+//	default=<value>
+//	             Unmarshal <value> into the field when the mapping
+//	             has no field key, before applying merge keys. The
+//	             value is resolved like a plain scalar and can't
+//	             contain commas.
+//
+//	comment=<text>
+//	             Emit <text> as a comment above the key. The comment
+//	             tag, e.g. `comment:"Listen port"`, does the same and
+//	             allows commas.
+//
+//	linecomment=<text>
+//	             Emit <text> as a comment on the line of the key. The
+//	             linecomment tag does the same and allows commas.
+//
+//	alias=<key>  Accept <key> in addition to the field key when
+//	             unmarshaling. May be given multiple times. Using
+//	             several keys of the same field in one mapping is an
+//	             error.
+//
 // In addition, if the key is "-", the field is ignored.
 //
 // For example:
@@ -58,9 +98,95 @@ type Marshaler interface {
 //	yaml.Marshal(&T{B: 2}) // Returns "b: 2\n"
 //	yaml.Marshal(&T{F: 1}} // Returns "a: 1\nb: 0\n"
 func Marshal(in any) (out []byte, err error) {
+	return marshal(in, EncodeOptions{})
+}
+
+// MarshalWithOptions is like Marshal, but uses given options.
+func MarshalWithOptions(in any, opts EncodeOptions) (out []byte, err error) {
+	return marshal(in, opts)
+}
+
+// EncodeOptions defines options for MarshalWithOptions.
+type EncodeOptions struct {
+	// Width is the preferred line width. Zero means lines are
+	// never wrapped.
+	//
+	// See Encoder.SetWidth.
+	Width int
+
+	// Canonical enables the canonical YAML output format.
+	Canonical bool
+
+	// ASCIIOnly escapes all non-ASCII characters.
+	ASCIIOnly bool
+
+	// LineBreak is the line break to use.
+	LineBreak LineBreak
+
+	// KeyLess reports whether map key a should be emitted before key b.
+	// If nil, keys are sorted in natural order.
+	//
+	// See Encoder.SetKeyLess.
+	KeyLess func(a, b any) bool
+
+	// KeyOrder lists map keys to emit first, in given order.
+	//
+	// See Encoder.SetKeyOrder.
+	KeyOrder []string
+
+	// Anchors enables anchors and aliases for pointers, maps and
+	// slices referenced more than once.
+	//
+	// See Encoder.UseAnchors.
+	Anchors bool
+
+	// JSONTags enables fallback to the json struct tag for fields
+	// without a yaml tag.
+	JSONTags bool
+}
+
+// LineBreak defines the line break used by the encoder.
+type LineBreak int
+
+const (
+	// LineBreakLF uses LF ("\n") line breaks. It is the default.
+	LineBreakLF LineBreak = iota
+	// LineBreakCRLF uses CRLF ("\r\n") line breaks.
+	LineBreakCRLF
+	// LineBreakCR uses CR ("\r") line breaks.
+	LineBreakCR
+)
+
+// String implements fmt.Stringer.
+func (l LineBreak) String() string {
+	switch l {
+	case LineBreakLF:
+		return "LF"
+	case LineBreakCRLF:
+		return "CRLF"
+	case LineBreakCR:
+		return "CR"
+	default:
+		return fmt.Sprintf("LineBreak(%d)", int(l))
+	}
+}
+
+func (l LineBreak) yaml() yaml_break_t {
+	switch l {
+	case LineBreakCRLF:
+		return yaml_CRLN_BREAK
+	case LineBreakCR:
+		return yaml_CR_BREAK
+	default:
+		return yaml_LN_BREAK
+	}
+}
+
+func marshal(in any, opts EncodeOptions) (out []byte, err error) {
 	defer handleErr(&err)
 	e := newEncoder()
 	defer e.destroy()
+	e.setOptions(opts)
 	e.marshalDoc("", reflect.ValueOf(in))
 	e.finish()
 	out = e.out
@@ -89,7 +215,21 @@ func NewEncoder(w io.Writer) *Encoder {
 // See the documentation for Marshal for details about the conversion of Go
 // values to YAML.
 func (e *Encoder) Encode(v any) (err error) {
+	return e.EncodeContext(context.Background(), v)
+}
+
+// EncodeContext is like Encode, but passes ctx to values implementing
+// MarshalerContext.
+func (e *Encoder) EncodeContext(ctx context.Context, v any) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	defer handleErr(&err)
+	e.encoder.checkStream()
+	e.encoder.ctx = ctx
+	defer func() {
+		e.encoder.ctx = nil
+	}()
 	e.encoder.marshalDoc("", reflect.ValueOf(v))
 	return nil
 }
@@ -120,10 +260,122 @@ func (e *Encoder) SetIndent(spaces int) {
 	e.encoder.indent = spaces
 }
 
+// SetWidth sets the preferred line width.
+//
+// Long scalars are folded at spaces to fit the width where possible.
+// If width is zero or negative, lines are never wrapped, which is the
+// default.
+func (e *Encoder) SetWidth(width int) {
+	e.encoder.width = width
+}
+
+// SetCanonical enables the canonical YAML output format, with explicit
+// tags and flow style everywhere.
+func (e *Encoder) SetCanonical(enable bool) {
+	e.encoder.canonical = enable
+}
+
+// SetASCIIOnly enables escaping of all non-ASCII characters.
+//
+// Scalars with non-ASCII characters are emitted as double-quoted strings
+// with escape sequences.
+func (e *Encoder) SetASCIIOnly(enable bool) {
+	e.encoder.asciiOnly = enable
+}
+
+// SetLineBreak sets the line break to use.
+func (e *Encoder) SetLineBreak(l LineBreak) {
+	e.encoder.lineBreak = l
+}
+
+// SetBufferSize sets the size of the output buffer.
+//
+// Encoded data is written to the underlying writer when the buffer is
+// full and at the end of each document. Sizes smaller than the default
+// of 128 bytes are ignored. It must be called before encoding.
+func (e *Encoder) SetBufferSize(size int) {
+	e.encoder.bufferSize = size
+}
+
+// SetKeyLess sets the function used to sort map keys.
+//
+// The function reports whether key a should be emitted before key b.
+// Keys are sorted in natural order by default, e.g. "a2" goes
+// before "a10".
+func (e *Encoder) SetKeyLess(less func(a, b any) bool) {
+	e.encoder.keyLess = less
+}
+
+// SetKeyOrder sets map keys to emit first, in given order.
+//
+// Other keys follow them, sorted as usual. For example:
+//
+//	enc.SetKeyOrder("apiVersion", "kind", "metadata")
+//
+// The order applies to all maps, including inlined ones, but not to
+// struct fields, which are emitted in declaration order.
+func (e *Encoder) SetKeyOrder(keys ...string) {
+	e.encoder.keyOrder = keyOrder(keys)
+}
+
+// UseAnchors enables anchors and aliases for pointers, maps and slices
+// referenced more than once.
+//
+// The first occurrence of such value is emitted with an anchor, and
+// later ones as aliases to it. For example:
+//
+//	shared := &Config{Name: "db"}
+//	enc.Encode(map[string]*Config{"primary": shared, "replica": shared})
+//
+// emits
+//
+//	primary:
+//	    &id001
+//	    name: db
+//	replica: *id001
+//
+// Cyclic values can't be encoded either way.
+func (e *Encoder) UseAnchors(enable bool) {
+	e.encoder.anchors = enable
+}
+
+// UseJSONTags enables fallback to the json struct tag for fields
+// without a yaml tag.
+//
+// The name and the omitempty flag of the json tag are respected, other
+// json flags are ignored. Embedded structs without a name are inlined,
+// like encoding/json does.
+func (e *Encoder) UseJSONTags(enable bool) {
+	e.encoder.jsonTags = enable
+}
+
+// TagEncodeFunc returns a value to encode in place of v.
+//
+// If the returned value is a Node, it is encoded as is, with the tag set.
+type TagEncodeFunc func(v reflect.Value) (any, error)
+
+// RegisterTag registers a tag emitted for values of given type.
+//
+// If f is not nil, the value returned by f is encoded in place of the original
+// value, like Marshaler does. Otherwise, the original value is encoded.
+//
+// For example:
+//
+//	enc.RegisterTag("!secret", reflect.TypeOf(Secret{}), func(v reflect.Value) (any, error) {
+//	    return v.Interface().(Secret).Ref, nil
+//	})
+func (e *Encoder) RegisterTag(tag string, typ reflect.Type, f TagEncodeFunc) {
+	if e.encoder.tags == nil {
+		e.encoder.tags = map[reflect.Type]tagEncoder{}
+	}
+	e.encoder.tags[typ] = tagEncoder{tag: shortTag(tag), f: f}
+}
+
 // Close closes the encoder by writing any remaining data.
 // It does not write a stream terminating string "...".
 func (e *Encoder) Close() (err error) {
 	defer handleErr(&err)
+	e.encoder.checkStream()
 	e.encoder.finish()
 	return nil
 }
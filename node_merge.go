@@ -0,0 +1,284 @@
+package yaml
+
+import (
+	"fmt"
+
+	"github.com/go-faster/errors"
+)
+
+// MergeStrategy defines how MergeNodes merges sequences.
+type MergeStrategy int
+
+const (
+	// MergePatch merges nodes using RFC 7386 JSON Merge Patch semantics:
+	// mappings are merged recursively, null values delete keys and other
+	// values, including sequences, replace the base value.
+	MergePatch MergeStrategy = iota
+	// MergeStrategic is like MergePatch, but sequences of mappings are
+	// merged by MergeOptions.MergeKey: items with the same key value are
+	// merged, new items are appended.
+	MergeStrategic
+)
+
+// String implements fmt.Stringer.
+func (s MergeStrategy) String() string {
+	switch s {
+	case MergePatch:
+		return "MergePatch"
+	case MergeStrategic:
+		return "MergeStrategic"
+	default:
+		return fmt.Sprintf("MergeStrategy(%d)", int(s))
+	}
+}
+
+// MergeOptions defines options for MergeNodes.
+type MergeOptions struct {
+	// Strategy is the merge strategy. Default is MergePatch.
+	Strategy MergeStrategy
+
+	// MergeKey is the mapping key used to match items of sequences of
+	// mappings, like "name". Required by MergeStrategic.
+	//
+	// Sequences with items lacking the key are replaced.
+	MergeKey string
+}
+
+// MergeNodes returns the result of merging overlay onto base.
+//
+// For example, merging
+//
+//	replicas: 3
+//	containers:
+//	    - name: app
+//	      image: app:v2
+//	debug: null
+//
+// onto
+//
+//	replicas: 1 # default
+//	containers:
+//	    - name: app
+//	      image: app:v1
+//	    - name: sidecar
+//	debug: true
+//
+// with MergeStrategic and MergeKey "name" results in
+//
+//	replicas: 3 # default
+//	containers:
+//	    - name: app
+//	      image: app:v2
+//	    - name: sidecar
+//
+// Comments of overlay nodes win, comments of base nodes are kept
+// otherwise. Aliases are followed, the result contains copies of aliased
+// nodes where they are merged. Aliases of deleted or replaced anchored
+// values are replaced by copies of the values. Merge keys are not
+// applied, "<<" is a regular key. Inputs are not modified.
+func MergeNodes(base, overlay *Node, opts MergeOptions) (_ *Node, err error) {
+	defer handleErr(&err)
+	switch opts.Strategy {
+	case MergePatch:
+	case MergeStrategic:
+		if opts.MergeKey == "" {
+			return nil, errors.New("merge key is required by MergeStrategic")
+		}
+	default:
+		return nil, errors.Errorf("unknown strategy %s", opts.Strategy)
+	}
+	if base == nil || overlay == nil {
+		return nil, errors.New("nil node")
+	}
+	if overlay.Kind == DocumentNode {
+		if len(overlay.Content) == 0 {
+			return copyNode(base, map[*Node]*Node{}), nil
+		}
+		overlay = overlay.Content[0]
+	}
+
+	m := &nodeMerger{opts: opts}
+	result := copyNode(base, map[*Node]*Node{})
+	slot := &result
+	if result.Kind == DocumentNode {
+		if len(result.Content) == 0 {
+			result.Content = []*Node{nil}
+		}
+		slot = &result.Content[0]
+	}
+	m.merge(slot, overlay)
+	for _, n := range m.removed {
+		resolveAliases(result, n)
+	}
+	return result, nil
+}
+
+type nodeMerger struct {
+	opts MergeOptions
+	// removed holds deleted and replaced base values.
+	removed []*Node
+}
+
+// merge merges overlay into the node in slot. The slot may be nil, if
+// there is no base value.
+func (m *nodeMerger) merge(slot **Node, overlay *Node) {
+	o := queryDeref(overlay)
+	base := *slot
+	var b *Node
+	if base != nil {
+		b = queryDeref(base)
+		defer func() {
+			if *slot != base {
+				m.removed = append(m.removed, base)
+			}
+		}()
+	}
+
+	switch {
+	case o.Kind == MappingNode:
+		var t *Node
+		if b != nil && b.Kind == MappingNode {
+			t = b
+			if base.Kind == AliasNode {
+				// Do not change the anchored node.
+				t = copyResolved(b, nil)
+			}
+		} else {
+			t = &Node{
+				Kind:   MappingNode,
+				Tag:    o.Tag,
+				Style:  o.Style,
+				Line:   o.Line,
+				Column: o.Column,
+			}
+		}
+		mergeComments(t, base, overlay)
+		for i := 0; i+1 < len(o.Content); i += 2 {
+			k, v := queryDeref(o.Content[i]), o.Content[i+1]
+			if k.Kind != ScalarNode {
+				fail(errors.Errorf("line %d: unsupported key kind %s", k.Line, k.Kind))
+			}
+			idx := patchKey(t, k.Value)
+			if isNullNode(v) {
+				if idx >= 0 {
+					m.removed = append(m.removed, t.Content[idx+1])
+					t.Content = append(t.Content[:idx], t.Content[idx+2:]...)
+				}
+				continue
+			}
+			if idx < 0 {
+				var value *Node
+				m.merge(&value, v)
+				t.Content = append(t.Content, copyResolved(o.Content[i], nil), value)
+				continue
+			}
+			mergeComments(t.Content[idx], t.Content[idx], o.Content[i])
+			m.merge(&t.Content[idx+1], v)
+		}
+		*slot = t
+	case m.opts.Strategy == MergeStrategic && o.Kind == SequenceNode &&
+		b != nil && b.Kind == SequenceNode && m.mergeable(b) && m.mergeable(o):
+		t := b
+		if base.Kind == AliasNode {
+			t = copyResolved(b, nil)
+		}
+		mergeComments(t, base, overlay)
+		for _, item := range o.Content {
+			key := m.itemKey(item)
+			j := 0
+			for ; j < len(t.Content); j++ {
+				if m.itemKey(t.Content[j]) == key {
+					break
+				}
+			}
+			if j < len(t.Content) {
+				m.merge(&t.Content[j], item)
+				continue
+			}
+			var value *Node
+			m.merge(&value, item)
+			t.Content = append(t.Content, value)
+		}
+		*slot = t
+	default:
+		t := copyResolved(overlay, nil)
+		mergeComments(t, base, overlay)
+		*slot = t
+	}
+}
+
+// mergeable reports whether all items of the sequence are mappings with
+// the merge key.
+func (m *nodeMerger) mergeable(n *Node) bool {
+	for _, item := range n.Content {
+		item = queryDeref(item)
+		if item.Kind != MappingNode {
+			return false
+		}
+		idx := patchKey(item, m.opts.MergeKey)
+		if idx < 0 || queryDeref(item.Content[idx+1]).Kind != ScalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+// itemKey returns the merge key value of the mergeable sequence item.
+func (m *nodeMerger) itemKey(item *Node) string {
+	item = queryDeref(item)
+	idx := patchKey(item, m.opts.MergeKey)
+	return queryDeref(item.Content[idx+1]).Value
+}
+
+func isNullNode(n *Node) bool {
+	n = queryDeref(n)
+	return n.Kind == ScalarNode && n.ShortTag() == nullTag
+}
+
+// mergeComments sets comments of n to comments of overlay, if present,
+// or base otherwise.
+func mergeComments(n, base, overlay *Node) {
+	pick := func(b, o string) string {
+		if o != "" {
+			return o
+		}
+		return b
+	}
+	var b Node
+	if base != nil {
+		b = *base
+	}
+	n.HeadComment = pick(b.HeadComment, overlay.HeadComment)
+	n.LineComment = pick(b.LineComment, overlay.LineComment)
+	n.FootComment = pick(b.FootComment, overlay.FootComment)
+}
+
+// copyResolved returns a deep copy of the node tree with aliases replaced
+// by copies of aliased nodes, and without anchors.
+func copyResolved(n *Node, visiting map[*Node]struct{}) *Node {
+	target := queryDeref(n)
+	c := *target
+	if target != n {
+		mergeComments(&c, target, n)
+	}
+	c.Anchor = ""
+	c.Alias = nil
+	if len(c.Content) == 0 {
+		return &c
+	}
+
+	if _, ok := visiting[target]; ok {
+		fail(errors.Errorf("line %d: alias cycle", n.Line))
+	}
+	if visiting == nil {
+		visiting = map[*Node]struct{}{}
+	}
+	visiting[target] = struct{}{}
+	content := make([]*Node, len(c.Content))
+	for i, child := range c.Content {
+		content[i] = copyResolved(child, visiting)
+	}
+	delete(visiting, target)
+	c.Content = content
+	return &c
+}
@@ -0,0 +1,202 @@
+package yaml_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-faster/yaml"
+)
+
+func TestMergeNodes(t *testing.T) {
+	const base = `# Base config.
+replicas: 1 # default
+containers:
+    -   name: app
+        image: app:v1 # pinned
+    -   name: sidecar
+debug: true
+labels:
+    &labels
+    app: web
+extra: *labels
+`
+	tests := []struct {
+		overlay string
+		opts    yaml.MergeOptions
+		result  string
+	}{
+		{
+			`replicas: 3
+containers:
+    -   name: app
+        image: app:v2
+debug: null
+`,
+			yaml.MergeOptions{Strategy: yaml.MergeStrategic, MergeKey: "name"},
+			`# Base config.
+replicas: 3 # default
+containers:
+    -   name: app
+        image: app:v2 # pinned
+    -   name: sidecar
+labels:
+    &labels
+    app: web
+extra: *labels
+`,
+		},
+		{
+			`containers:
+    -   name: app
+        image: app:v2
+`,
+			yaml.MergeOptions{},
+			`# Base config.
+replicas: 1 # default
+containers:
+    -   name: app
+        image: app:v2
+debug: true
+labels:
+    &labels
+    app: web
+extra: *labels
+`,
+		},
+		{
+			`replicas: 2 # scaled
+containers:
+    -   name: init # runs first
+        image: busybox
+        args: null
+`,
+			yaml.MergeOptions{Strategy: yaml.MergeStrategic, MergeKey: "name"},
+			`# Base config.
+replicas: 2 # scaled
+containers:
+    -   name: app
+        image: app:v1 # pinned
+    -   name: sidecar
+    -   name: init # runs first
+        image: busybox
+debug: true
+labels:
+    &labels
+    app: web
+extra: *labels
+`,
+		},
+		{
+			`extra: {tier: frontend}
+new: {a: 1, b: null}
+`,
+			yaml.MergeOptions{},
+			`# Base config.
+replicas: 1 # default
+containers:
+    -   name: app
+        image: app:v1 # pinned
+    -   name: sidecar
+debug: true
+labels:
+    &labels
+    app: web
+extra:
+    app: web
+    tier: frontend
+new: {a: 1}
+`,
+		},
+		{
+			`labels: {tier: frontend}`,
+			yaml.MergeOptions{},
+			`# Base config.
+replicas: 1 # default
+containers:
+    -   name: app
+        image: app:v1 # pinned
+    -   name: sidecar
+debug: true
+labels:
+    &labels
+    app: web
+    tier: frontend
+extra: *labels
+`,
+		},
+		{
+			`[1, 2]`,
+			yaml.MergeOptions{},
+			"[1, 2]\n",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var b, o yaml.Node
+			a.NoError(yaml.Unmarshal([]byte(base), &b))
+			a.NoError(yaml.Unmarshal([]byte(tt.overlay), &o))
+			r, err := yaml.MergeNodes(&b, &o, tt.opts)
+			a.NoError(err)
+			data, err := yaml.Marshal(r)
+			a.NoError(err)
+			a.Equal(tt.result, string(data))
+
+			// Inputs are not modified.
+			data, err = yaml.Marshal(&b)
+			a.NoError(err)
+			a.Equal(base, string(data))
+		})
+	}
+}
+
+func TestMergeNodesAnchors(t *testing.T) {
+	const base = "a: &x {v: 1}\nb: *x\nc: [*x]\n"
+	tests := []struct {
+		overlay string
+		result  string
+	}{
+		{"a: null", "b: {v: 1}\nc: [{v: 1}]\n"},
+		{"a: 2", "a: 2\nb: {v: 1}\nc: [{v: 1}]\n"},
+		{"a: [1]", "a: [1]\nb: {v: 1}\nc: [{v: 1}]\n"},
+		{"a: {w: 2}", "a: &x {v: 1, w: 2}\nb: *x\nc: [*x]\n"},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var b, o yaml.Node
+			a.NoError(yaml.Unmarshal([]byte(base), &b))
+			a.NoError(yaml.Unmarshal([]byte(tt.overlay), &o))
+			r, err := yaml.MergeNodes(&b, &o, yaml.MergeOptions{})
+			a.NoError(err)
+			data, err := yaml.Marshal(r)
+			a.NoError(err)
+			a.Equal(tt.result, string(data))
+
+			// Result is a valid document.
+			var v any
+			a.NoError(yaml.Unmarshal(data, &v))
+		})
+	}
+}
+
+func TestMergeNodesErrors(t *testing.T) {
+	a := require.New(t)
+
+	var b, o yaml.Node
+	a.NoError(yaml.Unmarshal([]byte("a: 1"), &b))
+	a.NoError(yaml.Unmarshal([]byte("{[1]: 2}"), &o))
+
+	_, err := yaml.MergeNodes(&b, &o, yaml.MergeOptions{Strategy: yaml.MergeStrategic})
+	a.EqualError(err, "merge key is required by MergeStrategic")
+	_, err = yaml.MergeNodes(&b, &o, yaml.MergeOptions{Strategy: 10})
+	a.EqualError(err, "unknown strategy MergeStrategy(10)")
+	_, err = yaml.MergeNodes(&b, &o, yaml.MergeOptions{})
+	a.EqualError(err, "line 1: unsupported key kind Sequence")
+}
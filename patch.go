@@ -0,0 +1,497 @@
+package yaml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/go-faster/jx"
+)
+
+// ErrPatchTestFailed is returned by ApplyJSONPatch when a test operation
+// fails.
+var ErrPatchTestFailed = errors.New("test operation failed")
+
+// ApplyJSONPatch applies the RFC 6902 JSON Patch to the node tree.
+//
+// Supported operations are add, remove, replace, move, copy and test.
+// Paths are RFC 6901 JSON Pointers, relative to the content of the
+// document node. For example:
+//
+//	[
+//	    {"op": "replace", "path": "/spec/replicas", "value": 3},
+//	    {"op": "add", "path": "/spec/ports/-", "value": {"port": 443}}
+//	]
+//
+// Nodes which are not touched by the patch keep their comments, styles
+// and ordering. A replaced value keeps the comments of the previous one.
+// Aliases are followed, so changing a value through an alias changes the
+// anchored value. Aliases of a replaced or removed anchored value are
+// replaced by copies of the value. Merge keys are not applied, "<<" is
+// a regular key.
+//
+// Operations are applied in order. If any of them fails, doc is left
+// unchanged.
+func ApplyJSONPatch(doc *Node, patch []byte) error {
+	ops, err := parseJSONPatch(patch)
+	if err != nil {
+		return errors.Wrap(err, "parse patch")
+	}
+
+	for i, op := range ops {
+		if err := op.validate(); err != nil {
+			return errors.Wrapf(err, "operation %d", i)
+		}
+	}
+
+	root := copyNode(doc, map[*Node]*Node{})
+	for i, op := range ops {
+		if err := op.apply(root); err != nil {
+			return errors.Wrapf(err, "operation %d (%s %q)", i, op.Op, op.Path)
+		}
+	}
+	*doc = *root
+	return nil
+}
+
+type patchOp struct {
+	Op    string
+	Path  string
+	From  string
+	Value *Node
+
+	hasPath, hasFrom bool
+}
+
+func parseJSONPatch(data []byte) (ops []patchOp, _ error) {
+	d := jx.DecodeBytes(data)
+	if err := d.Arr(func(d *jx.Decoder) error {
+		var op patchOp
+		if err := d.ObjBytes(func(d *jx.Decoder, key []byte) (err error) {
+			switch string(key) {
+			case "op":
+				op.Op, err = d.Str()
+			case "path":
+				op.Path, err = d.Str()
+				op.hasPath = true
+			case "from":
+				op.From, err = d.Str()
+				op.hasFrom = true
+			case "value":
+				op.Value = new(Node)
+				err = readJSON(d, op.Value)
+			default:
+				err = d.Skip()
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+		ops = append(ops, op)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+func (op patchOp) validate() error {
+	switch {
+	case op.Op == "":
+		return errors.New("missing \"op\"")
+	case !op.hasPath:
+		return errors.Errorf("%s: missing \"path\"", op.Op)
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return errors.Errorf("%s: missing \"value\"", op.Op)
+		}
+	case "move", "copy":
+		if !op.hasFrom {
+			return errors.Errorf("%s: missing \"from\"", op.Op)
+		}
+	case "remove":
+	default:
+		return errors.Errorf("unknown operation %q", op.Op)
+	}
+	return nil
+}
+
+func (op patchOp) apply(doc *Node) error {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+	switch op.Op {
+	case "add":
+		return patchAdd(doc, path, copyNode(op.Value, map[*Node]*Node{}))
+	case "remove":
+		v, err := patchRemove(doc, path)
+		if err != nil {
+			return err
+		}
+		resolveAliases(doc, v)
+		return nil
+	case "replace":
+		if _, err := patchGet(doc, path); err != nil {
+			return err
+		}
+		return patchAdd(doc, path, copyNode(op.Value, map[*Node]*Node{}))
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return err
+		}
+		if from.String() == path.String() {
+			return nil
+		}
+		if from.isPrefix(path) {
+			return errors.Errorf("can't move %q into itself", op.From)
+		}
+		v, err := patchRemove(doc, from)
+		if err != nil {
+			return err
+		}
+		return patchAdd(doc, path, v)
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return err
+		}
+		v, err := patchGet(doc, from)
+		if err != nil {
+			return err
+		}
+		return patchAdd(doc, path, copyNode(v, map[*Node]*Node{}))
+	case "test":
+		v, err := patchGet(doc, path)
+		if err != nil {
+			return err
+		}
+		ok, err := jsonEqual(v, op.Value)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrPatchTestFailed
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// pointer is a parsed RFC 6901 JSON Pointer.
+type pointer []string
+
+func parsePointer(s string) (pointer, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, errors.Errorf("invalid JSON pointer %q", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, tok := range tokens {
+		if !strings.Contains(tok, "~") {
+			continue
+		}
+		var sb strings.Builder
+		for j := 0; j < len(tok); j++ {
+			c := tok[j]
+			if c == '~' {
+				if j+1 >= len(tok) || tok[j+1] != '0' && tok[j+1] != '1' {
+					return nil, errors.Errorf("invalid JSON pointer %q", s)
+				}
+				j++
+				c = "~/"[tok[j]-'0']
+			}
+			sb.WriteByte(c)
+		}
+		tokens[i] = sb.String()
+	}
+	return tokens, nil
+}
+
+func (p pointer) String() string {
+	var sb strings.Builder
+	for _, tok := range p {
+		sb.WriteByte('/')
+		tok = strings.ReplaceAll(tok, "~", "~0")
+		tok = strings.ReplaceAll(tok, "/", "~1")
+		sb.WriteString(tok)
+	}
+	return sb.String()
+}
+
+// isPrefix reports whether p is a proper prefix of other.
+func (p pointer) isPrefix(other pointer) bool {
+	if len(p) >= len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// patchRoot returns the root value of the document.
+func patchRoot(doc *Node) *Node {
+	if doc.Kind == DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// patchSetRoot replaces the root value of the document.
+func patchSetRoot(doc, v *Node) {
+	switch {
+	case doc.Kind != DocumentNode:
+		*doc = *v
+	case len(doc.Content) == 0:
+		doc.Content = []*Node{v}
+	default:
+		keepComments(v, doc.Content[0])
+		doc.Content[0] = v
+	}
+}
+
+func patchGet(doc *Node, path pointer) (*Node, error) {
+	n := patchRoot(doc)
+	for i, tok := range path {
+		n = queryDeref(n)
+		switch n.Kind {
+		case MappingNode:
+			idx := patchKey(n, tok)
+			if idx < 0 {
+				return nil, errors.Errorf("path %q not found", path[:i+1])
+			}
+			n = n.Content[idx+1]
+		case SequenceNode:
+			idx, err := patchIndex(tok, len(n.Content)-1)
+			if err != nil {
+				return nil, errors.Wrapf(err, "path %q", path[:i+1])
+			}
+			n = n.Content[idx]
+		default:
+			return nil, errors.Errorf("path %q not found", path[:i+1])
+		}
+	}
+	return n, nil
+}
+
+// patchParent returns the container of the last path element.
+func patchParent(doc *Node, path pointer) (*Node, error) {
+	parent, err := patchGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	parent = queryDeref(parent)
+	if parent.Kind != MappingNode && parent.Kind != SequenceNode {
+		return nil, errors.Errorf("path %q is not a mapping or sequence", path[:len(path)-1])
+	}
+	return parent, nil
+}
+
+func patchAdd(doc *Node, path pointer, v *Node) error {
+	if len(path) == 0 {
+		patchSetRoot(doc, v)
+		return nil
+	}
+	parent, err := patchParent(doc, path)
+	if err != nil {
+		return err
+	}
+	tok := path[len(path)-1]
+
+	if parent.Kind == MappingNode {
+		if idx := patchKey(parent, tok); idx >= 0 {
+			prev := parent.Content[idx+1]
+			keepComments(v, prev)
+			parent.Content[idx+1] = v
+			resolveAliases(doc, prev)
+			return nil
+		}
+		k := new(Node)
+		readJSONString(k, tok)
+		parent.Content = append(parent.Content, k, v)
+		return nil
+	}
+
+	idx := len(parent.Content)
+	if tok != "-" {
+		idx, err = patchIndex(tok, len(parent.Content))
+		if err != nil {
+			return errors.Wrapf(err, "path %q", path)
+		}
+	}
+	parent.Content = append(parent.Content, nil)
+	copy(parent.Content[idx+1:], parent.Content[idx:])
+	parent.Content[idx] = v
+	return nil
+}
+
+func patchRemove(doc *Node, path pointer) (*Node, error) {
+	if len(path) == 0 {
+		return nil, errors.New("can't remove the root")
+	}
+	parent, err := patchParent(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	tok := path[len(path)-1]
+
+	if parent.Kind == MappingNode {
+		idx := patchKey(parent, tok)
+		if idx < 0 {
+			return nil, errors.Errorf("path %q not found", path)
+		}
+		v := parent.Content[idx+1]
+		parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+		return v, nil
+	}
+
+	idx, err := patchIndex(tok, len(parent.Content)-1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "path %q", path)
+	}
+	v := parent.Content[idx]
+	parent.Content = append(parent.Content[:idx], parent.Content[idx+1:]...)
+	return v, nil
+}
+
+// patchKey returns the index of the key in the mapping or -1.
+func patchKey(n *Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := queryDeref(n.Content[i]); k.Kind == ScalarNode && k.Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// patchIndex parses the sequence index, which must not exceed max.
+func patchIndex(tok string, max int) (int, error) {
+	if tok == "" || len(tok) > 1 && tok[0] == '0' || strings.TrimLeft(tok, "0123456789") != "" {
+		return 0, errors.Errorf("invalid index %q", tok)
+	}
+	idx, err := strconv.Atoi(tok)
+	if err != nil || idx > max {
+		return 0, errors.Errorf("index %s out of range", tok)
+	}
+	return idx, nil
+}
+
+// keepComments copies comments of the replaced node to the new one,
+// unless it has its own.
+func keepComments(n, prev *Node) {
+	if n.HeadComment == "" && n.LineComment == "" && n.FootComment == "" {
+		n.HeadComment = prev.HeadComment
+		n.LineComment = prev.LineComment
+		n.FootComment = prev.FootComment
+	}
+}
+
+// resolveAliases replaces aliases of anchored nodes of the removed tree by
+// copies of the aliased nodes, so the document does not refer to anchors
+// which are not defined anymore.
+func resolveAliases(doc, removed *Node) {
+	anchors := map[*Node]struct{}{}
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		if n.Anchor != "" {
+			anchors[n] = struct{}{}
+		}
+		for _, child := range n.Content {
+			collect(child)
+		}
+	}
+	collect(removed)
+	if len(anchors) == 0 {
+		return
+	}
+
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for i, child := range n.Content {
+			if child.Kind != AliasNode {
+				walk(child)
+				continue
+			}
+			if _, ok := anchors[child.Alias]; ok {
+				n.Content[i] = copyResolved(child, nil)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// copyNode returns a deep copy of the node tree.
+//
+// Aliases of the copy point to the copied nodes.
+func copyNode(n *Node, copies map[*Node]*Node) *Node {
+	if n == nil {
+		return nil
+	}
+	if c, ok := copies[n]; ok {
+		return c
+	}
+	c := new(Node)
+	copies[n] = c
+	*c = *n
+	if n.Content != nil {
+		c.Content = make([]*Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child, copies)
+		}
+	}
+	c.Alias = copyNode(n.Alias, copies)
+	return c
+}
+
+// jsonEqual reports whether the nodes represent the same JSON value.
+func jsonEqual(a, b *Node) (bool, error) {
+	var av, bv any
+	if err := a.Decode(&av); err != nil {
+		return false, err
+	}
+	if err := b.Decode(&bv); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(jsonNormalize(av), jsonNormalize(bv)), nil
+}
+
+// jsonNormalize converts numbers to float64 and maps to map[string]any.
+func jsonNormalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonNormalize(e)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonNormalize(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = jsonNormalize(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,209 @@
+package yaml_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-faster/yaml"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	const input = `# Service config.
+name: web # service name
+spec:
+    # Number of instances.
+    replicas: 1 # scaled by hand
+    ports:
+        - 80
+        - 443
+    labels: {app: web}
+    "a/b~c": x
+`
+	tests := []struct {
+		patch  string
+		result string
+	}{
+		{
+			`[{"op": "replace", "path": "/spec/replicas", "value": 3}]`,
+			`# Service config.
+name: web # service name
+spec:
+    # Number of instances.
+    replicas: 3 # scaled by hand
+    ports:
+        - 80
+        - 443
+    labels: {app: web}
+    "a/b~c": x
+`,
+		},
+		{
+			`[
+				{"op": "add", "path": "/spec/ports/-", "value": 8080},
+				{"op": "add", "path": "/spec/ports/0", "value": 22},
+				{"op": "add", "path": "/spec/env", "value": {"DEBUG": "true", "list": [1]}},
+				{"op": "remove", "path": "/spec/labels"}
+			]`,
+			`# Service config.
+name: web # service name
+spec:
+    # Number of instances.
+    replicas: 1 # scaled by hand
+    ports:
+        - 22
+        - 80
+        - 443
+        - 8080
+    "a/b~c": x
+    env:
+        DEBUG: "true"
+        list:
+            - 1
+`,
+		},
+		{
+			`[
+				{"op": "move", "from": "/spec/ports/1", "path": "/spec/ports/0"},
+				{"op": "copy", "from": "/spec/labels", "path": "/labels"},
+				{"op": "remove", "path": "/spec/a~1b~0c"},
+				{"op": "test", "path": "/labels", "value": {"app": "web"}},
+				{"op": "test", "path": "/spec/replicas", "value": 1.0}
+			]`,
+			`# Service config.
+name: web # service name
+spec:
+    # Number of instances.
+    replicas: 1 # scaled by hand
+    ports:
+        - 443
+        - 80
+    labels: {app: web}
+labels: {app: web}
+`,
+		},
+		{
+			`[{"op": "replace", "path": "", "value": [1]}]`,
+			"- 1\n",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var doc yaml.Node
+			a.NoError(yaml.Unmarshal([]byte(input), &doc))
+			a.NoError(yaml.ApplyJSONPatch(&doc, []byte(tt.patch)))
+			data, err := yaml.Marshal(&doc)
+			a.NoError(err)
+			a.Equal(tt.result, string(data))
+		})
+	}
+}
+
+func TestApplyJSONPatchAnchors(t *testing.T) {
+	tests := []struct {
+		input  string
+		patch  string
+		result string
+	}{
+		{
+			"a: &x 1\nb: *x # alias\n",
+			`[{"op": "replace", "path": "/a", "value": 2}]`,
+			"a: 2\nb: 1 # alias\n",
+		},
+		{
+			"a: &x 1\nb: *x\n",
+			`[{"op": "add", "path": "/a", "value": 2}]`,
+			"a: 2\nb: 1\n",
+		},
+		{
+			"a: &x 1\nb: [*x, *x]\n",
+			`[{"op": "remove", "path": "/a"}]`,
+			"b: [1, 1]\n",
+		},
+		{
+			"a: &x {k: &y 1}\nb: *x\nc: *y\n",
+			`[{"op": "remove", "path": "/a"}]`,
+			"b: {k: 1}\nc: 1\n",
+		},
+		{
+			"a: &x 1\nb: *x\n",
+			`[{"op": "replace", "path": "/b", "value": 2}]`,
+			"a: &x 1\nb: 2\n",
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var doc yaml.Node
+			a.NoError(yaml.Unmarshal([]byte(tt.input), &doc))
+			a.NoError(yaml.ApplyJSONPatch(&doc, []byte(tt.patch)))
+			data, err := yaml.Marshal(&doc)
+			a.NoError(err)
+			a.Equal(tt.result, string(data))
+
+			// Result is a valid document.
+			var v any
+			a.NoError(yaml.Unmarshal(data, &v))
+		})
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	const input = "a: 1\nb: [1, 2]\n"
+	tests := []struct {
+		patch string
+		err   string
+	}{
+		{`{}`, `parse patch: "[" expected: unexpected byte 123 '{' at 0`},
+		{`[{"path": "/a"}]`, `operation 0: missing "op"`},
+		{`[{"op": "add", "value": 1}]`, `operation 0: add: missing "path"`},
+		{`[{"op": "add", "path": "/a"}]`, `operation 0: add: missing "value"`},
+		{`[{"op": "move", "path": "/a"}]`, `operation 0: move: missing "from"`},
+		{`[{"op": "merge", "path": "/a"}]`, `operation 0: unknown operation "merge"`},
+		{`[{"op": "remove", "path": "a"}]`, `operation 0 (remove "a"): invalid JSON pointer "a"`},
+		{`[{"op": "remove", "path": "/c~2"}]`, `operation 0 (remove "/c~2"): invalid JSON pointer "/c~2"`},
+		{`[{"op": "remove", "path": "/c"}]`, `operation 0 (remove "/c"): path "/c" not found`},
+		{`[{"op": "replace", "path": "/c/d", "value": 1}]`, `operation 0 (replace "/c/d"): path "/c" not found`},
+		{`[{"op": "add", "path": "/a/d", "value": 1}]`, `operation 0 (add "/a/d"): path "/a" is not a mapping or sequence`},
+		{`[{"op": "add", "path": "/b/3", "value": 1}]`, `operation 0 (add "/b/3"): path "/b/3": index 3 out of range`},
+		{`[{"op": "remove", "path": "/b/01"}]`, `operation 0 (remove "/b/01"): path "/b/01": invalid index "01"`},
+		{`[{"op": "remove", "path": "/b/-"}]`, `operation 0 (remove "/b/-"): path "/b/-": invalid index "-"`},
+		{`[{"op": "remove", "path": ""}]`, `operation 0 (remove ""): can't remove the root`},
+		{`[{"op": "move", "from": "/b", "path": "/b/0"}]`, `operation 0 (move "/b/0"): can't move "/b" into itself`},
+		{
+			`[{"op": "remove", "path": "/a"}, {"op": "test", "path": "/b", "value": [1]}]`,
+			`operation 1 (test "/b"): test operation failed`,
+		},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			var doc yaml.Node
+			a.NoError(yaml.Unmarshal([]byte(input), &doc))
+			err := yaml.ApplyJSONPatch(&doc, []byte(tt.patch))
+			a.EqualError(err, tt.err)
+
+			// Document is not changed on error.
+			data, err := yaml.Marshal(&doc)
+			a.NoError(err)
+			a.Equal("a: 1\nb: [1, 2]\n", string(data))
+		})
+	}
+
+	t.Run("TestFailed", func(t *testing.T) {
+		a := require.New(t)
+
+		var doc yaml.Node
+		a.NoError(yaml.Unmarshal([]byte(input), &doc))
+		err := yaml.ApplyJSONPatch(&doc, []byte(`[{"op": "test", "path": "/a", "value": "1"}]`))
+		a.ErrorIs(err, yaml.ErrPatchTestFailed)
+	})
+}
@@ -0,0 +1,127 @@
+package yaml
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/multierr"
+)
+
+// PathElem is an element of a document path: a mapping key or
+// a sequence index.
+type PathElem struct {
+	// Key is the mapping key. Valid if IsIndex is false.
+	Key string
+	// Index is the sequence index. Valid if IsIndex is true.
+	Index int
+	// IsIndex reports whether the element is a sequence index.
+	IsIndex bool
+}
+
+// Path is a path from the document root to a node, for example
+// spec.containers[2].ports[0].
+type Path []PathElem
+
+// String returns the path in the form accepted by Node.Query.
+//
+// Keys which are not made of letters, digits, '_', '-' and '/' are
+// quoted, like ['app.kubernetes.io/name'].
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, e := range p {
+		switch {
+		case e.IsIndex:
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(e.Index))
+			sb.WriteByte(']')
+		case isPlainPathKey(e.Key):
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(e.Key)
+		default:
+			sb.WriteString("['")
+			for _, r := range e.Key {
+				if r == '\'' || r == '\\' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteRune(r)
+			}
+			sb.WriteString("']")
+		}
+	}
+	return sb.String()
+}
+
+func isPlainPathKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+// keyElem returns the path element of the mapping key node.
+func keyElem(n *Node) PathElem {
+	for i := 0; n.Kind == AliasNode && n.Alias != nil && i < 1000; i++ {
+		n = n.Alias
+	}
+	return PathElem{Key: n.Value}
+}
+
+// currentPath returns a copy of the path of the node being decoded.
+func (d *decoder) currentPath() Path {
+	if len(d.path) == 0 {
+		return nil
+	}
+	return append(Path(nil), d.path...)
+}
+
+// fail fails with given error, setting the path of the UnmarshalError.
+func (d *decoder) fail(err error) {
+	if e, ok := err.(*UnmarshalError); ok && e.Path == nil {
+		e.Path = d.currentPath()
+	}
+	fail(err)
+}
+
+// nestedErr prefixes paths of errors returned by Node.Decode called from
+// a custom unmarshaler with the path of the node being decoded.
+func (d *decoder) nestedErr(err error) error {
+	if len(d.path) == 0 || err == nil {
+		return err
+	}
+	switch e := err.(type) {
+	case *UnmarshalError:
+		c := *e
+		c.Path = append(d.currentPath(), e.Path...)
+		switch inner := e.Err.(type) {
+		case *UnknownFieldError:
+			ic := *inner
+			ic.Path = c.Path
+			c.Err = &ic
+		case *MissingFieldError:
+			ic := *inner
+			ic.Path = c.Path
+			c.Err = &ic
+		case *DuplicateKeyError:
+			ic := *inner
+			ic.Path = c.Path
+			c.Err = &ic
+		}
+		return &c
+	case *TypeError:
+		errs := multierr.Errors(e.Group)
+		for i, err := range errs {
+			errs[i] = d.nestedErr(err)
+		}
+		return &TypeError{Group: multierr.Combine(errs...)}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,719 @@
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query returns nodes matching the path expression.
+//
+// The expression is a subset of JSONPath:
+//
+//	$                 the root node, may be omitted
+//	.key, ['key']     the value of the mapping key
+//	.*, [*]           all mapping values or sequence items
+//	[0], [-1]         the sequence item by index, negative counts from the end
+//	[0,2], ['a','b']  several items or keys
+//	[1:3], [::2]      the slice of a sequence, [start:end:step]
+//	..key, ..[0]      recursive descent, the selector applies to the
+//	                  node and all of its descendants
+//	[?(@.name == "x")]
+//	                  mapping values or sequence items matching the
+//	                  filter, which supports ==, !=, <, <=, >, >=, &&,
+//	                  || and !. A path without comparison tests that
+//	                  the path exists.
+//
+// For example:
+//
+//	nodes, err := doc.Query(`spec.containers[?(@.name == "app")].ports[*]`)
+//
+// Aliases are followed and merge keys are applied, like decoding does,
+// so the result contains the aliased nodes themselves, with their
+// Line and Column. If n is a document node, the query starts from its
+// content.
+func (n *Node) Query(expr string) (_ []*Node, err error) {
+	defer handleErr(&err)
+	p := &queryParser{query: expr}
+	steps := p.parseRoot()
+	if n == nil {
+		return nil, nil
+	}
+	root := n
+	if root.Kind == DocumentNode {
+		if len(root.Content) == 0 {
+			return nil, nil
+		}
+		root = root.Content[0]
+	}
+	root = queryDeref(root)
+	q := &queryContext{root: root}
+	return q.eval(root, steps), nil
+}
+
+// queryStep is a single step of the path.
+type queryStep struct {
+	// descend applies the selector to all descendants of the node.
+	descend bool
+	sel     querySelector
+}
+
+type querySelector interface {
+	// selectNodes appends nodes selected from n to out.
+	selectNodes(q *queryContext, n *Node, out []*Node) []*Node
+}
+
+type queryContext struct {
+	root *Node
+}
+
+func (q *queryContext) eval(n *Node, steps []queryStep) []*Node {
+	nodes := []*Node{n}
+	for _, step := range steps {
+		var next []*Node
+		for _, n := range nodes {
+			if !step.descend {
+				next = step.sel.selectNodes(q, n, next)
+				continue
+			}
+			queryDescendants(n, nil, func(d *Node) {
+				next = step.sel.selectNodes(q, d, next)
+			})
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// queryDeref follows aliases.
+func queryDeref(n *Node) *Node {
+	for i := 0; n.Kind == AliasNode && n.Alias != nil; i++ {
+		if i > 1000 {
+			// Cyclic aliases.
+			break
+		}
+		n = n.Alias
+	}
+	return n
+}
+
+// queryPairs calls f for each key and value of the mapping, applying merge
+// keys. Keys of the mapping override merged keys, and earlier merged
+// mappings override later ones.
+func queryPairs(n *Node, f func(k, v *Node)) {
+	seen := map[string]struct{}{}
+	visiting := map[*Node]struct{}{}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if _, ok := visiting[n]; ok {
+			return
+		}
+		visiting[n] = struct{}{}
+		defer delete(visiting, n)
+
+		var merges []*Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if isMerge(k) {
+				merges = append(merges, v)
+				continue
+			}
+			if dk := queryDeref(k); dk.Kind == ScalarNode {
+				if _, ok := seen[dk.Value]; ok {
+					continue
+				}
+				seen[dk.Value] = struct{}{}
+			}
+			f(k, queryDeref(v))
+		}
+		for _, m := range merges {
+			m = queryDeref(m)
+			switch m.Kind {
+			case MappingNode:
+				walk(m)
+			case SequenceNode:
+				for _, item := range m.Content {
+					if item = queryDeref(item); item.Kind == MappingNode {
+						walk(item)
+					}
+				}
+			}
+		}
+	}
+	walk(n)
+}
+
+// queryChildren calls f for each mapping value or sequence item.
+func queryChildren(n *Node, f func(c *Node)) {
+	switch n.Kind {
+	case MappingNode:
+		queryPairs(n, func(_, v *Node) {
+			f(v)
+		})
+	case SequenceNode:
+		for _, c := range n.Content {
+			f(queryDeref(c))
+		}
+	}
+}
+
+// queryDescendants calls f for n and all of its descendants.
+func queryDescendants(n *Node, visiting map[*Node]struct{}, f func(d *Node)) {
+	if _, ok := visiting[n]; ok {
+		// Cyclic aliases.
+		return
+	}
+	f(n)
+	if n.Kind != MappingNode && n.Kind != SequenceNode {
+		return
+	}
+	if visiting == nil {
+		visiting = map[*Node]struct{}{}
+	}
+	visiting[n] = struct{}{}
+	queryChildren(n, func(c *Node) {
+		queryDescendants(c, visiting, f)
+	})
+	delete(visiting, n)
+}
+
+type queryWildcard struct{}
+
+func (queryWildcard) selectNodes(_ *queryContext, n *Node, out []*Node) []*Node {
+	queryChildren(n, func(c *Node) {
+		out = append(out, c)
+	})
+	return out
+}
+
+type queryKeys []string
+
+func (s queryKeys) selectNodes(_ *queryContext, n *Node, out []*Node) []*Node {
+	if n.Kind != MappingNode {
+		return out
+	}
+	for _, key := range s {
+		queryPairs(n, func(k, v *Node) {
+			if k := queryDeref(k); k.Kind == ScalarNode && k.Value == key {
+				out = append(out, v)
+			}
+		})
+	}
+	return out
+}
+
+type queryIndexes []int
+
+func (s queryIndexes) selectNodes(_ *queryContext, n *Node, out []*Node) []*Node {
+	if n.Kind != SequenceNode {
+		return out
+	}
+	for _, i := range s {
+		if i < 0 {
+			i += len(n.Content)
+		}
+		if i >= 0 && i < len(n.Content) {
+			out = append(out, queryDeref(n.Content[i]))
+		}
+	}
+	return out
+}
+
+type querySlice struct {
+	start, end, step int
+	// hasStart and hasEnd are false if the bound is omitted.
+	hasStart, hasEnd bool
+}
+
+func (s querySlice) selectNodes(_ *queryContext, n *Node, out []*Node) []*Node {
+	if n.Kind != SequenceNode {
+		return out
+	}
+	l := len(n.Content)
+	bound := func(i, lower, upper int) int {
+		if i < 0 {
+			i += l
+		}
+		if i < lower {
+			return lower
+		}
+		if i > upper {
+			return upper
+		}
+		return i
+	}
+	if s.step > 0 {
+		start, end := 0, l
+		if s.hasStart {
+			start = bound(s.start, 0, l)
+		}
+		if s.hasEnd {
+			end = bound(s.end, 0, l)
+		}
+		for i := start; i < end; i += s.step {
+			out = append(out, queryDeref(n.Content[i]))
+		}
+		return out
+	}
+	start, end := l-1, -1
+	if s.hasStart {
+		start = bound(s.start, -1, l-1)
+	}
+	if s.hasEnd {
+		end = bound(s.end, -1, l-1)
+	}
+	for i := start; i > end; i += s.step {
+		out = append(out, queryDeref(n.Content[i]))
+	}
+	return out
+}
+
+type queryFilter struct {
+	expr queryExpr
+}
+
+func (s queryFilter) selectNodes(q *queryContext, n *Node, out []*Node) []*Node {
+	queryChildren(n, func(c *Node) {
+		if s.expr.match(q, c) {
+			out = append(out, c)
+		}
+	})
+	return out
+}
+
+// queryExpr is a filter expression.
+type queryExpr interface {
+	match(q *queryContext, n *Node) bool
+}
+
+type queryOr [2]queryExpr
+
+func (e queryOr) match(q *queryContext, n *Node) bool {
+	return e[0].match(q, n) || e[1].match(q, n)
+}
+
+type queryAnd [2]queryExpr
+
+func (e queryAnd) match(q *queryContext, n *Node) bool {
+	return e[0].match(q, n) && e[1].match(q, n)
+}
+
+type queryNot struct {
+	expr queryExpr
+}
+
+func (e queryNot) match(q *queryContext, n *Node) bool {
+	return !e.expr.match(q, n)
+}
+
+// queryExists matches if the path selects any node.
+type queryExists struct {
+	path queryOperand
+}
+
+func (e queryExists) match(q *queryContext, n *Node) bool {
+	return len(e.path.nodes(q, n)) > 0
+}
+
+type queryCompare struct {
+	op          string
+	left, right queryOperand
+}
+
+func (e queryCompare) match(q *queryContext, n *Node) bool {
+	l, ok := e.left.value(q, n)
+	if !ok {
+		return false
+	}
+	r, ok := e.right.value(q, n)
+	if !ok {
+		return false
+	}
+	return queryCompareValues(e.op, l, r)
+}
+
+// queryOperand is a path or a literal.
+type queryOperand struct {
+	// relative path starts from the current node (@) instead of the
+	// root ($).
+	relative bool
+	steps    []queryStep
+	// literal is the value of the literal operand.
+	literal   any
+	isLiteral bool
+}
+
+func (o queryOperand) nodes(q *queryContext, n *Node) []*Node {
+	if !o.relative {
+		n = q.root
+	}
+	return q.eval(n, o.steps)
+}
+
+func (o queryOperand) value(q *queryContext, n *Node) (any, bool) {
+	if o.isLiteral {
+		return o.literal, true
+	}
+	nodes := o.nodes(q, n)
+	if len(nodes) == 0 || nodes[0].Kind != ScalarNode {
+		return nil, false
+	}
+	return queryScalarValue(nodes[0]), true
+}
+
+// queryScalarValue returns the resolved value of the scalar.
+func queryScalarValue(n *Node) any {
+	switch tag := n.ShortTag(); tag {
+	case intTag, floatTag, boolTag, nullTag:
+		if rtag, v := resolve("", n.Value); rtag == tag {
+			return v
+		}
+	}
+	return n.Value
+}
+
+func queryNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func queryCompareValues(op string, l, r any) bool {
+	var c int
+	if ln, ok := queryNumber(l); ok {
+		rn, ok := queryNumber(r)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case ln < rn:
+			c = -1
+		case ln > rn:
+			c = 1
+		}
+	} else if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return op == "!="
+		}
+		c = strings.Compare(ls, rs)
+	} else {
+		// Booleans and nulls are only equal or not.
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		default:
+			return false
+		}
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+type queryParser struct {
+	query string
+	pos   int
+}
+
+func (p *queryParser) failf(msgf string, args ...any) {
+	fail(&QueryError{
+		Query:  p.query,
+		Offset: p.pos,
+		Msg:    fmt.Sprintf(msgf, args...),
+	})
+}
+
+func (p *queryParser) eof() bool {
+	return p.pos >= len(p.query)
+}
+
+func (p *queryParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.query[p.pos]
+}
+
+func (p *queryParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *queryParser) consume(s string) bool {
+	if strings.HasPrefix(p.query[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(s string) {
+	p.skipSpaces()
+	if !p.consume(s) {
+		p.failf("expected %q", s)
+	}
+}
+
+// parseRoot parses the whole query.
+func (p *queryParser) parseRoot() []queryStep {
+	var steps []queryStep
+	switch c := p.peek(); {
+	case c == '$':
+		p.pos++
+	case c != 0 && c != '.' && c != '[':
+		// Leading key without a dot, like "spec.containers".
+		steps = append(steps, queryStep{sel: queryKeys{p.parseName()}})
+	}
+	steps = append(steps, p.parseSteps()...)
+	if !p.eof() {
+		p.failf("unexpected %q", p.peek())
+	}
+	return steps
+}
+
+// parseSteps parses dot and bracket selectors.
+func (p *queryParser) parseSteps() (steps []queryStep) {
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			var step queryStep
+			if p.consume(".") {
+				step.descend = true
+				if p.peek() == '[' {
+					step.sel = p.parseBracket()
+					steps = append(steps, step)
+					continue
+				}
+			}
+			if p.consume("*") {
+				step.sel = queryWildcard{}
+			} else {
+				step.sel = queryKeys{p.parseName()}
+			}
+			steps = append(steps, step)
+		case '[':
+			steps = append(steps, queryStep{sel: p.parseBracket()})
+		default:
+			return steps
+		}
+	}
+}
+
+func (p *queryParser) parseName() string {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(".[]()=!<>&|,'\" \t", rune(p.peek())) {
+		p.pos++
+	}
+	if start == p.pos {
+		p.failf("expected key")
+	}
+	return p.query[start:p.pos]
+}
+
+func (p *queryParser) parseBracket() querySelector {
+	p.expect("[")
+	p.skipSpaces()
+	var sel querySelector
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		sel = queryWildcard{}
+	case c == '?':
+		p.pos++
+		sel = queryFilter{expr: p.parseOr()}
+	case c == '\'' || c == '"':
+		var keys queryKeys
+		for {
+			p.skipSpaces()
+			keys = append(keys, p.parseString())
+			p.skipSpaces()
+			if !p.consume(",") {
+				break
+			}
+		}
+		sel = keys
+	default:
+		sel = p.parseIndexes()
+	}
+	p.expect("]")
+	return sel
+}
+
+func (p *queryParser) parseIndexes() querySelector {
+	start, hasStart := p.parseInt()
+	p.skipSpaces()
+	if p.peek() == ':' {
+		s := querySlice{start: start, hasStart: hasStart, step: 1}
+		p.pos++
+		p.skipSpaces()
+		s.end, s.hasEnd = p.parseInt()
+		p.skipSpaces()
+		if p.consume(":") {
+			p.skipSpaces()
+			if step, ok := p.parseInt(); ok {
+				if step == 0 {
+					p.failf("slice step can't be zero")
+				}
+				s.step = step
+			}
+		}
+		return s
+	}
+	if !hasStart {
+		p.failf("expected index")
+	}
+	indexes := queryIndexes{start}
+	for {
+		p.skipSpaces()
+		if !p.consume(",") {
+			return indexes
+		}
+		p.skipSpaces()
+		i, ok := p.parseInt()
+		if !ok {
+			p.failf("expected index")
+		}
+		indexes = append(indexes, i)
+	}
+}
+
+func (p *queryParser) parseInt() (int, bool) {
+	start := p.pos
+	p.consume("-")
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	i, err := strconv.Atoi(p.query[start:p.pos])
+	if err != nil {
+		p.pos = start
+		p.failf("invalid index %q", p.query[start:p.pos])
+	}
+	return i, true
+}
+
+func (p *queryParser) parseString() string {
+	quote := p.peek()
+	if quote != '\'' && quote != '"' {
+		p.failf("expected string")
+	}
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			p.failf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == quote:
+			return sb.String()
+		case c == '\\' && !p.eof():
+			c = p.peek()
+			p.pos++
+		}
+		sb.WriteByte(c)
+	}
+}
+
+func (p *queryParser) parseOr() queryExpr {
+	e := p.parseAnd()
+	for {
+		p.skipSpaces()
+		if !p.consume("||") {
+			return e
+		}
+		e = queryOr{e, p.parseAnd()}
+	}
+}
+
+func (p *queryParser) parseAnd() queryExpr {
+	e := p.parseUnary()
+	for {
+		p.skipSpaces()
+		if !p.consume("&&") {
+			return e
+		}
+		e = queryAnd{e, p.parseUnary()}
+	}
+}
+
+func (p *queryParser) parseUnary() queryExpr {
+	p.skipSpaces()
+	switch {
+	case p.consume("!"):
+		return queryNot{expr: p.parseUnary()}
+	case p.consume("("):
+		e := p.parseOr()
+		p.expect(")")
+		return e
+	}
+
+	start := p.pos
+	left := p.parseOperand()
+	p.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			p.skipSpaces()
+			return queryCompare{op: op, left: left, right: p.parseOperand()}
+		}
+	}
+	if left.isLiteral {
+		p.pos = start
+		p.failf("expected path")
+	}
+	return queryExists{path: left}
+}
+
+func (p *queryParser) parseOperand() queryOperand {
+	switch c := p.peek(); c {
+	case '@', '$':
+		p.pos++
+		return queryOperand{relative: c == '@', steps: p.parseSteps()}
+	case '\'', '"':
+		return queryOperand{literal: p.parseString(), isLiteral: true}
+	}
+
+	start := p.pos
+	for !p.eof() && strings.ContainsRune("+-.0123456789_abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", rune(p.peek())) {
+		p.pos++
+	}
+	lit := p.query[start:p.pos]
+	switch tag, v := resolve("", lit); {
+	case lit == "":
+		p.failf("expected operand")
+	case tag == intTag || tag == floatTag || tag == boolTag || tag == nullTag:
+		return queryOperand{literal: v, isLiteral: true}
+	default:
+		p.pos = start
+		p.failf("unexpected literal %q", lit)
+	}
+	return queryOperand{}
+}
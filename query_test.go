@@ -0,0 +1,148 @@
+package yaml_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-faster/yaml"
+)
+
+const queryInput = `defaults: &defaults
+  image: nginx
+  pull: always
+spec:
+  containers:
+    - name: app
+      <<: *defaults
+      ports: [80, 443]
+      replicas: 3
+    - name: sidecar
+      image: envoy
+      ports: [9901]
+      replicas: 1
+    - name: init
+      image: busybox
+  selector:
+    app.kubernetes.io/name: web
+`
+
+func TestNodeQuery(t *testing.T) {
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(queryInput), &doc))
+
+	// nodeString returns the value of the scalar node or the position of
+	// the collection node.
+	nodeString := func(n *yaml.Node) string {
+		if n.Kind == yaml.ScalarNode {
+			return n.Value
+		}
+		return fmt.Sprintf("%s@%d:%d", n.Kind, n.Line, n.Column)
+	}
+
+	tests := []struct {
+		query  string
+		result []string
+	}{
+		{`$`, []string{"Mapping@1:1"}},
+		{`spec.containers[0].name`, []string{"app"}},
+		{`$.spec.containers[1].name`, []string{"sidecar"}},
+		{`$['spec']["containers"][-1].name`, []string{"init"}},
+		{`spec.containers[*].name`, []string{"app", "sidecar", "init"}},
+		{`spec.containers[0,2].name`, []string{"app", "init"}},
+		{`spec.containers[1:].name`, []string{"sidecar", "init"}},
+		{`spec.containers[:-1].name`, []string{"app", "sidecar"}},
+		{`spec.containers[::2].name`, []string{"app", "init"}},
+		{`spec.containers[::-1].name`, []string{"init", "sidecar", "app"}},
+		{`spec.containers[5].name`, nil},
+		{`spec.selector['app.kubernetes.io/name']`, []string{"web"}},
+		{`spec.containers[0].ports`, []string{"Sequence@8:14"}},
+		{`spec.containers[0].*`, []string{"app", "Sequence@8:14", "3", "nginx", "always"}},
+		{`spec.missing`, nil},
+		{`spec.containers.name`, nil},
+
+		// Merge keys.
+		{`spec.containers[*].image`, []string{"nginx", "envoy", "busybox"}},
+		{`spec.containers[0].image`, []string{"nginx"}},
+
+		// Recursive descent.
+		{`$..image`, []string{"nginx", "nginx", "envoy", "busybox"}},
+		{`spec..name`, []string{"app", "sidecar", "init"}},
+		{`spec..ports[0]`, []string{"80", "9901"}},
+		{`spec.containers[0]..*`, []string{"app", "Sequence@8:14", "3", "nginx", "always", "80", "443"}},
+
+		// Filters.
+		{`spec.containers[?(@.name == "sidecar")].image`, []string{"envoy"}},
+		{`spec.containers[?(@.name == 'app')].pull`, []string{"always"}},
+		{`spec.containers[?(@.replicas > 1)].name`, []string{"app"}},
+		{`spec.containers[?(@.replicas <= 3)].name`, []string{"app", "sidecar"}},
+		{`spec.containers[?(@.replicas == 3.0)].name`, []string{"app"}},
+		{`spec.containers[?(@.replicas)].name`, []string{"app", "sidecar"}},
+		{`spec.containers[?(!@.replicas)].name`, []string{"init"}},
+		{`spec.containers[?(@.image == "nginx" || @.name == "init")].name`, []string{"app", "init"}},
+		{`spec.containers[?(@.replicas >= 1 && @.image != "nginx")].name`, []string{"sidecar"}},
+		{`spec.containers[?(@.ports[1])].name`, []string{"app"}},
+		{`spec.containers[?(@.image == $.defaults.image)].name`, []string{"app"}},
+		{`spec.containers[?(@.replicas == "3")].name`, nil},
+		{`spec.containers[?(@.ports == 80)].name`, nil},
+		{`spec.containers[*].ports[?(@ > 100)]`, []string{"443", "9901"}},
+		{`$..[?(@.name == "init")].image`, []string{"busybox"}},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			nodes, err := doc.Query(tt.query)
+			a.NoError(err)
+			var result []string
+			for _, n := range nodes {
+				result = append(result, nodeString(n))
+			}
+			a.Equal(tt.result, result)
+		})
+	}
+
+	t.Run("Position", func(t *testing.T) {
+		a := require.New(t)
+
+		nodes, err := doc.Query(`spec.containers[0].image`)
+		a.NoError(err)
+		a.Len(nodes, 1)
+		// Merged value keeps the position of the anchored mapping.
+		a.Equal(2, nodes[0].Line)
+		a.Equal(10, nodes[0].Column)
+	})
+}
+
+func TestNodeQueryErrors(t *testing.T) {
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(queryInput), &doc))
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`spec.`, `yaml: query "spec.": offset 5: expected key`},
+		{`spec[0`, `yaml: query "spec[0": offset 6: expected "]"`},
+		{`spec['a`, `yaml: query "spec['a": offset 7: unterminated string`},
+		{`spec[::0]`, `yaml: query "spec[::0]": offset 8: slice step can't be zero`},
+		{`spec[]`, `yaml: query "spec[]": offset 5: expected index`},
+		{`spec]`, `yaml: query "spec]": offset 4: unexpected ']'`},
+		{`spec[?(@.a == foo)]`, `yaml: query "spec[?(@.a == foo)]": offset 14: unexpected literal "foo"`},
+		{`spec[?(1)]`, `yaml: query "spec[?(1)]": offset 7: expected path`},
+		{`spec[?(@.a == 1]`, `yaml: query "spec[?(@.a == 1]": offset 15: expected ")"`},
+	}
+	for i, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("Test%d", i+1), func(t *testing.T) {
+			a := require.New(t)
+
+			_, err := doc.Query(tt.query)
+			var qerr *yaml.QueryError
+			a.ErrorAs(err, &qerr)
+			a.EqualError(err, tt.err)
+		})
+	}
+}
@@ -23,6 +23,7 @@
 package yaml
 
 import (
+	"fmt"
 	"io"
 )
 
@@ -98,6 +99,12 @@ func yaml_parser_update_raw_buffer(parser *yaml_parser_t) bool {
 	// Call the read handler to fill the buffer.
 	size_read, err := parser.read_handler(parser, parser.raw_buffer[len(parser.raw_buffer):cap(parser.raw_buffer)])
 	parser.raw_buffer = parser.raw_buffer[:len(parser.raw_buffer)+size_read]
+	parser.input_read += size_read
+	if parser.max_bytes > 0 && parser.input_read > parser.max_bytes {
+		parser.problem_err = ErrMaxBytes
+		return yaml_parser_set_reader_error(parser,
+			fmt.Sprintf("exceeded max document size of %d bytes", parser.max_bytes), parser.max_bytes, -1)
+	}
 	if err == io.EOF {
 		parser.eof = true
 	} else if err != nil {
@@ -957,8 +957,17 @@ func yaml_parser_remove_simple_key(parser *yaml_parser_t) bool {
 	return true
 }
 
-// max_flow_level limits the flow_level
-const max_flow_level = 10000
+// default_max_depth limits the flow_level and the indents stack size,
+// unless parser.max_depth is set.
+const default_max_depth = 10000
+
+// yaml_parser_max_depth returns the maximum depth of the document.
+func yaml_parser_max_depth(parser *yaml_parser_t) int {
+	if parser.max_depth > 0 {
+		return parser.max_depth
+	}
+	return default_max_depth
+}
 
 // Increase the flow level and resize the simple key list if needed.
 func yaml_parser_increase_flow_level(parser *yaml_parser_t) bool {
@@ -972,10 +981,11 @@ func yaml_parser_increase_flow_level(parser *yaml_parser_t) bool {
 
 	// Increase the flow level.
 	parser.flow_level++
-	if parser.flow_level > max_flow_level {
+	if max_depth := yaml_parser_max_depth(parser); parser.flow_level > max_depth {
+		parser.problem_err = ErrMaxDepth
 		return yaml_parser_set_scanner_error(parser,
 			"while increasing flow level", parser.simple_keys[len(parser.simple_keys)-1].mark,
-			fmt.Sprintf("exceeded max depth of %d", max_flow_level))
+			fmt.Sprintf("exceeded max depth of %d", max_depth))
 	}
 	return true
 }
@@ -991,9 +1001,6 @@ func yaml_parser_decrease_flow_level(parser *yaml_parser_t) bool {
 	return true
 }
 
-// max_indents limits the indents stack size
-const max_indents = 10000
-
 // Push the current indentation level to the stack and set the new level
 // the current column is greater than the indentation level.  In this case,
 // append or insert the specified token into the token queue.
@@ -1008,10 +1015,11 @@ func yaml_parser_roll_indent(parser *yaml_parser_t, column, number int, typ yaml
 		// indentation level.
 		parser.indents = append(parser.indents, parser.indent)
 		parser.indent = column
-		if len(parser.indents) > max_indents {
+		if max_depth := yaml_parser_max_depth(parser); len(parser.indents) > max_depth {
+			parser.problem_err = ErrMaxDepth
 			return yaml_parser_set_scanner_error(parser,
 				"while increasing indent level", parser.simple_keys[len(parser.simple_keys)-1].mark,
-				fmt.Sprintf("exceeded max depth of %d", max_indents))
+				fmt.Sprintf("exceeded max depth of %d", max_depth))
 		}
 
 		// Create a token and insert it into the queue.
@@ -20,6 +20,27 @@ import (
 	"unicode"
 )
 
+// rankedKeyList sorts keys by rank first, then by given comparator,
+// or in natural order if less is nil.
+type rankedKeyList struct {
+	keys keyList
+	rank func(k reflect.Value) int
+	less func(a, b any) bool
+}
+
+func (l rankedKeyList) Len() int      { return len(l.keys) }
+func (l rankedKeyList) Swap(i, j int) { l.keys.Swap(i, j) }
+func (l rankedKeyList) Less(i, j int) bool {
+	a, b := l.keys[i], l.keys[j]
+	if ra, rb := l.rank(a), l.rank(b); ra != rb {
+		return ra < rb
+	}
+	if l.less != nil && a.CanInterface() && b.CanInterface() {
+		return l.less(a.Interface(), b.Interface())
+	}
+	return l.keys.Less(i, j)
+}
+
 type keyList []reflect.Value
 
 func (l keyList) Len() int      { return len(l) }
@@ -1,10 +1,14 @@
 package yaml
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"reflect"
 
 	"go.uber.org/multierr"
+
+	"github.com/go-faster/errors"
 )
 
 // The Unmarshaler interface may be implemented by types to customize their
@@ -13,6 +17,12 @@ type Unmarshaler interface {
 	UnmarshalYAML(value *Node) error
 }
 
+// UnmarshalerContext is like Unmarshaler, but also receives the context
+// passed to Decoder.DecodeContext or Node.DecodeContext.
+type UnmarshalerContext interface {
+	UnmarshalYAML(ctx context.Context, value *Node) error
+}
+
 type obsoleteUnmarshaler interface {
 	UnmarshalYAML(unmarshal func(any) error) error
 }
@@ -51,13 +61,273 @@ type obsoleteUnmarshaler interface {
 // See the documentation of Marshal for the format of tags and a list of
 // supported tag options.
 func Unmarshal(in []byte, out any) (err error) {
-	return unmarshal(in, out)
+	return unmarshal(in, out, DecodeOptions{})
+}
+
+// UnmarshalWithOptions is like Unmarshal, but uses given options.
+func UnmarshalWithOptions(in []byte, out any, opts DecodeOptions) (err error) {
+	return unmarshal(in, out, opts)
+}
+
+// UnmarshalAll decodes all documents found within the in byte slice
+// into the slice pointed to by out, one element per document.
+//
+// The slice is truncated before decoding, so previous elements are
+// dropped. The out parameter must be a non-nil pointer to a slice.
+//
+// See the documentation of Unmarshal for details about the
+// conversion of YAML into a Go value.
+func UnmarshalAll(in []byte, out any) (err error) {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("yaml: UnmarshalAll requires a non-nil pointer to a slice, got %T", out)
+	}
+	v = v.Elem()
+
+	defer handleErr(&err)
+	d := newDecoder(DecodeOptions{})
+	p := newParser(in)
+	defer p.destroy()
+
+	v.SetLen(0)
+	et := v.Type().Elem()
+	for {
+		node := p.parse()
+		if node == nil {
+			break
+		}
+		e := reflect.New(et).Elem()
+		d.unmarshal(node, e)
+		v.Set(reflect.Append(v, e))
+	}
+	if len(d.terrors) > 0 {
+		return &TypeError{
+			Group: multierr.Combine(d.terrors...),
+		}
+	}
+	return nil
+}
+
+// DocumentIterator iterates over documents of a YAML stream.
+//
+// Use Documents to create one.
+type DocumentIterator struct {
+	parser *parser
+	index  int
+	node   *Node
+	err    error
+}
+
+// Documents returns an iterator over all documents found within the in
+// byte slice.
+//
+// For example:
+//
+//	docs := yaml.Documents(data)
+//	defer docs.Close()
+//	for docs.Next() {
+//	    fmt.Println(docs.Index(), docs.Node().Line)
+//	}
+//	if err := docs.Err(); err != nil {
+//	    return err
+//	}
+func Documents(in []byte) *DocumentIterator {
+	return &DocumentIterator{
+		parser: newParser(in),
+		index:  -1,
+	}
+}
+
+// Next parses the next document. It returns false when there are no more
+// documents or an error occurred.
+func (it *DocumentIterator) Next() bool {
+	if it.parser == nil {
+		return false
+	}
+	node, err := it.parse()
+	if node == nil || err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+	it.index++
+	it.node = node
+	return true
+}
+
+func (it *DocumentIterator) parse() (node *Node, err error) {
+	defer handleErr(&err)
+	return it.parser.parse(), nil
+}
+
+// Close releases the resources of the iterator. It must be called if
+// the iterator is not read to the end, and is a no-op otherwise.
+//
+// After Close, Next returns false.
+func (it *DocumentIterator) Close() {
+	it.node = nil
+	if it.parser != nil {
+		it.parser.destroy()
+		it.parser = nil
+	}
+}
+
+// Index returns the zero-based index of the current document.
+func (it *DocumentIterator) Index() int {
+	return it.index
+}
+
+// Node returns the current document as a DocumentNode.
+func (it *DocumentIterator) Node() *Node {
+	return it.node
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *DocumentIterator) Err() error {
+	return it.err
+}
+
+// UnmarshalWithLimits is like Unmarshal, but fails if the document
+// exceeds any of given limits.
+func UnmarshalWithLimits(in []byte, out any, limits Limits) (err error) {
+	return unmarshal(in, out, DecodeOptions{Limits: limits})
+}
+
+// Limits defines resource limits for decoding untrusted input.
+//
+// The zero value of any field means the default limit. Every exceeded limit
+// is reported as an error, which matches the corresponding sentinel error
+// using errors.Is.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of flow collections and
+	// block indentation levels. Default is 10000.
+	//
+	// Exceeding it fails with ErrMaxDepth.
+	MaxDepth int
+	// MaxAliases is the maximum number of values decoded through
+	// alias expansion. By default, the number of aliased values is limited
+	// relative to the total number of decoded values.
+	//
+	// Exceeding it fails with ErrMaxAliases.
+	MaxAliases int
+	// MaxBytes is the maximum size of the input in bytes. For a Decoder,
+	// the limit applies to the whole stream. Default is no limit.
+	//
+	// Exceeding it fails with ErrMaxBytes.
+	MaxBytes int
+	// MaxScalarLength is the maximum length of a scalar value in bytes.
+	// Default is no limit.
+	//
+	// Exceeding it fails with ErrMaxScalarLength.
+	MaxScalarLength int
+	// MaxNodes is the maximum number of nodes in a single document.
+	// Default is no limit.
+	//
+	// Exceeding it fails with ErrMaxNodes.
+	MaxNodes int
+}
+
+// DuplicateKeyPolicy defines how the decoder handles repeated keys
+// in a single mapping.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysError reports every repeated key as a *DuplicateKeyError
+	// in the returned *TypeError. This is the default.
+	DuplicateKeysError DuplicateKeyPolicy = iota
+	// DuplicateKeysLastWins silently uses the value of the last occurrence.
+	DuplicateKeysLastWins
+	// DuplicateKeysFirstWins silently uses the value of the first occurrence.
+	DuplicateKeysFirstWins
+	// DuplicateKeysWarn uses the value of the last occurrence and reports
+	// every repeated key as a warning.
+	DuplicateKeysWarn
+)
+
+// String implements fmt.Stringer.
+func (p DuplicateKeyPolicy) String() string {
+	switch p {
+	case DuplicateKeysError:
+		return "Error"
+	case DuplicateKeysLastWins:
+		return "LastWins"
+	case DuplicateKeysFirstWins:
+		return "FirstWins"
+	case DuplicateKeysWarn:
+		return "Warn"
+	default:
+		return fmt.Sprintf("DuplicateKeyPolicy(%d)", int(p))
+	}
+}
+
+// TagDecodeFunc decodes a node with a custom tag into out.
+//
+// The out value is settable and never a pointer. If the target is an empty
+// interface, out is a new value of the type used for the node by default:
+// string for scalars, []any for sequences and a map or MapSlice for
+// mappings. It is stored in the target after the call.
+//
+// If a *TypeError is returned, decoding continues and its errors are
+// reported along with other errors.
+type TagDecodeFunc func(n *Node, out reflect.Value) error
+
+// DecodeOptions defines options for UnmarshalWithOptions and
+// Node.DecodeWithOptions.
+type DecodeOptions struct {
+	// KnownFields ensures that the keys in decoded mappings
+	// exist as fields in the struct being decoded into.
+	KnownFields bool
+
+	// CaseInsensitive enables case-insensitive matching of mapping keys
+	// to struct fields.
+	//
+	// See Decoder.CaseInsensitive.
+	CaseInsensitive bool
+
+	// OrderedMaps makes MapSlice the default representation of mappings
+	// decoded into interface values.
+	OrderedMaps bool
+
+	// DuplicateKeys defines how repeated mapping keys are handled.
+	DuplicateKeys DuplicateKeyPolicy
+
+	// JSONTags enables fallback to the json struct tag for fields
+	// without a yaml tag.
+	JSONTags bool
+
+	// ExpandEnv enables expansion of environment variable references
+	// in plain and double-quoted scalars.
+	//
+	// See Decoder.ExpandEnv.
+	ExpandEnv bool
+
+	// LookupEnv is used to look up environment variables when ExpandEnv
+	// is set. If nil, os.LookupEnv is used.
+	LookupEnv func(key string) (string, bool)
+
+	// OnWarning is called for every non-fatal problem found during
+	// decoding, e.g. a repeated key when DuplicateKeys is DuplicateKeysWarn.
+	//
+	// Warnings are *UnmarshalError values.
+	OnWarning func(err error)
+
+	// Tags maps tags to functions decoding nodes with that tag.
+	//
+	// See Decoder.RegisterTag.
+	Tags map[string]TagDecodeFunc
+
+	// Limits defines resource limits of the decoder.
+	//
+	// Node.DecodeWithOptions respects only the MaxAliases limit, since
+	// the node is already parsed.
+	Limits Limits
 }
 
 // A Decoder reads and decodes YAML values from an input stream.
 type Decoder struct {
-	parser      *parser
-	knownFields bool
+	parser  *parser
+	include *includer
+	opts    DecodeOptions
 }
 
 // NewDecoder returns a new decoder that reads from r.
@@ -73,7 +343,100 @@ func NewDecoder(r io.Reader) *Decoder {
 // KnownFields ensures that the keys in decoded mappings to
 // exist as fields in the struct being decoded into.
 func (dec *Decoder) KnownFields(enable bool) {
-	dec.knownFields = enable
+	dec.opts.KnownFields = enable
+}
+
+// CaseInsensitive enables case-insensitive matching of mapping keys
+// to struct fields and their aliases.
+//
+// A key matching a field exactly is preferred over a case-insensitive
+// match of another field.
+func (dec *Decoder) CaseInsensitive(enable bool) {
+	dec.opts.CaseInsensitive = enable
+}
+
+// UseOrderedMaps makes MapSlice the default representation of mappings
+// decoded into interface values, instead of map[string]any and map[any]any.
+//
+// The order of keys is preserved, so re-encoding such value keeps
+// the original order.
+func (dec *Decoder) UseOrderedMaps(enable bool) {
+	dec.opts.OrderedMaps = enable
+}
+
+// DuplicateKeys sets the policy for handling repeated mapping keys.
+//
+// By default, repeated keys are reported as errors.
+func (dec *Decoder) DuplicateKeys(policy DuplicateKeyPolicy) {
+	dec.opts.DuplicateKeys = policy
+}
+
+// UseJSONTags enables fallback to the json struct tag for fields
+// without a yaml tag.
+//
+// The name and the omitempty flag of the json tag are respected, other
+// json flags are ignored. Embedded structs without a name are inlined,
+// like encoding/json does.
+func (dec *Decoder) UseJSONTags(enable bool) {
+	dec.opts.JSONTags = enable
+}
+
+// RegisterTag registers a function which decodes nodes with given tag.
+//
+// The function is called for every node carrying the tag before
+// the decoder tries any other way to decode the node, including
+// the Unmarshaler interface.
+//
+// For example:
+//
+//	dec.RegisterTag("!secret", func(n *yaml.Node, out reflect.Value) error {
+//	    secret, err := store.Lookup(n.Value)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    out.SetString(secret)
+//	    return nil
+//	})
+func (dec *Decoder) RegisterTag(tag string, f TagDecodeFunc) {
+	if dec.opts.Tags == nil {
+		dec.opts.Tags = map[string]TagDecodeFunc{}
+	}
+	dec.opts.Tags[shortTag(tag)] = f
+}
+
+// ExpandEnv enables expansion of environment variable references
+// in plain and double-quoted scalars.
+//
+// The following forms are supported:
+//
+//	${VAR}          value of VAR, or empty string if VAR is not set
+//	${VAR:-default} value of VAR, or default if VAR is not set or empty
+//	${VAR:?message} value of VAR, or an error if VAR is not set or empty
+//
+// Use $${ to write a literal ${. Expanded plain scalars are resolved
+// again, so "port: ${PORT}" can be decoded into an int.
+func (dec *Decoder) ExpandEnv(enable bool) {
+	dec.opts.ExpandEnv = enable
+}
+
+// LookupEnv sets the function used to look up environment variables.
+//
+// By default, os.LookupEnv is used.
+func (dec *Decoder) LookupEnv(f func(key string) (string, bool)) {
+	dec.opts.LookupEnv = f
+}
+
+// SetLimits sets resource limits of the decoder.
+//
+// It should be called before the first call to Decode.
+func (dec *Decoder) SetLimits(limits Limits) {
+	dec.opts.Limits = limits
+}
+
+// OnWarning sets the function called for every non-fatal problem
+// found during decoding.
+func (dec *Decoder) OnWarning(f func(err error)) {
+	dec.opts.OnWarning = f
 }
 
 // Decode reads the next YAML-encoded value from its input
@@ -82,13 +445,28 @@ func (dec *Decoder) KnownFields(enable bool) {
 // See the documentation for Unmarshal for details about the
 // conversion of YAML into a Go value.
 func (dec *Decoder) Decode(v any) (err error) {
-	d := newDecoder()
-	d.knownFields = dec.knownFields
+	return dec.DecodeContext(context.Background(), v)
+}
+
+// DecodeContext is like Decode, but passes ctx to values implementing
+// UnmarshalerContext.
+//
+// Decoding is aborted with the context error when ctx is cancelled.
+func (dec *Decoder) DecodeContext(ctx context.Context, v any) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d := newDecoder(dec.opts)
+	d.ctx = ctx
 	defer handleErr(&err)
+	dec.parser.setLimits(dec.opts.Limits)
 	node := dec.parser.parse()
 	if node == nil {
 		return io.EOF
 	}
+	if dec.include != nil {
+		dec.include.resolve(node, dec.opts.Limits)
+	}
 	out := reflect.ValueOf(v)
 	if out.Kind() == reflect.Ptr && !out.IsNil() {
 		out = out.Elem()
@@ -107,7 +485,24 @@ func (dec *Decoder) Decode(v any) (err error) {
 // See the documentation for Unmarshal for details about the
 // conversion of YAML into a Go value.
 func (n *Node) Decode(v any) (err error) {
-	d := newDecoder()
+	return n.DecodeWithOptions(v, DecodeOptions{})
+}
+
+// DecodeWithOptions is like Decode, but uses given options.
+func (n *Node) DecodeWithOptions(v any, opts DecodeOptions) (err error) {
+	return n.DecodeContext(context.Background(), v, opts)
+}
+
+// DecodeContext is like DecodeWithOptions, but passes ctx to values
+// implementing UnmarshalerContext.
+//
+// Decoding is aborted with the context error when ctx is cancelled.
+func (n *Node) DecodeContext(ctx context.Context, v any, opts DecodeOptions) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d := newDecoder(opts)
+	d.ctx = ctx
 	defer handleErr(&err)
 	out := reflect.ValueOf(v)
 	if out.Kind() == reflect.Ptr && !out.IsNil() {
@@ -122,11 +517,12 @@ func (n *Node) Decode(v any) (err error) {
 	return nil
 }
 
-func unmarshal(in []byte, out any) (err error) {
+func unmarshal(in []byte, out any, opts DecodeOptions) (err error) {
 	defer handleErr(&err)
-	d := newDecoder()
+	d := newDecoder(opts)
 	p := newParser(in)
 	defer p.destroy()
+	p.setLimits(opts.Limits)
 	node := p.parse()
 	if node != nil {
 		v := reflect.ValueOf(out)
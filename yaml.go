@@ -21,6 +21,7 @@
 package yaml
 
 import (
+	"encoding"
 	"reflect"
 	"strings"
 	"sync"
@@ -39,6 +40,10 @@ type structInfo struct {
 	FieldsMap  map[string]fieldInfo
 	FieldsList []fieldInfo
 
+	// FoldMap maps lowercased keys and aliases to fields, used
+	// for case-insensitive matching.
+	FoldMap map[string]fieldInfo
+
 	// InlineMap is the number of the field in the struct that
 	// contains an ,inline map, or -1 if there's none.
 	InlineMap int
@@ -46,13 +51,32 @@ type structInfo struct {
 	// InlineUnmarshalers holds indexes to inlined fields that
 	// contain unmarshaler values.
 	InlineUnmarshalers [][]int
+
+	// Required is true if any field is marked as required.
+	Required bool
+
+	// Defaults is true if any field, including fields of nested
+	// structs, has a default value.
+	Defaults bool
 }
 
 type fieldInfo struct {
 	Key       string
+	Aliases   []string
 	Num       int
 	OmitEmpty bool
 	Flow      bool
+	Required  bool
+	// String is true if the numeric or boolean field is encoded
+	// as a quoted string.
+	String bool
+	// Default holds the text of the default value, if any.
+	Default string
+	// Style is the scalar style requested for string values.
+	Style yaml_scalar_style_t
+	// HeadComment and LineComment hold comments emitted for the key.
+	HeadComment string
+	LineComment string
 	// ID holds the unique field identifier, so we can cheaply
 	// check for field duplicates without maintaining an extra map.
 	ID int
@@ -61,20 +85,54 @@ type fieldInfo struct {
 	Inline []int
 }
 
+type structKey struct {
+	Type     reflect.Type
+	JSONTags bool
+}
+
 var (
-	structMap       = make(map[reflect.Type]*structInfo)
-	fieldMapMutex   sync.RWMutex
-	unmarshalerType reflect.Type
+	structMap              = make(map[structKey]*structInfo)
+	fieldMapMutex          sync.RWMutex
+	unmarshalerType        reflect.Type
+	unmarshalerContextType reflect.Type
+	textUnmarshalerType    reflect.Type
 )
 
 func init() {
 	var v Unmarshaler
 	unmarshalerType = reflect.ValueOf(&v).Elem().Type()
+	var cv UnmarshalerContext
+	unmarshalerContextType = reflect.ValueOf(&cv).Elem().Type()
+	var tv encoding.TextUnmarshaler
+	textUnmarshalerType = reflect.ValueOf(&tv).Elem().Type()
+}
+
+// fieldTag returns the tag of the field and whether it was taken
+// from the json tag.
+//
+// If jsonTags is true, the json tag is used when the field has no yaml tag.
+func fieldTag(field reflect.StructField, jsonTags bool) (tag string, fromJSON bool) {
+	if tag, ok := field.Tag.Lookup("yaml"); ok {
+		return tag, false
+	}
+	if jsonTags {
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			return tag, true
+		}
+	}
+	if strings.IndexByte(string(field.Tag), ':') < 0 {
+		return string(field.Tag), false
+	}
+	return "", false
 }
 
-func getStructInfo(st reflect.Type) (*structInfo, error) {
+// getStructInfo returns serialization details of given struct type.
+//
+// If jsonTags is true, the json tag is used for fields without a yaml tag.
+func getStructInfo(st reflect.Type, jsonTags bool) (*structInfo, error) {
+	key := structKey{Type: st, JSONTags: jsonTags}
 	fieldMapMutex.RLock()
-	sinfo, found := structMap[st]
+	sinfo, found := structMap[key]
 	fieldMapMutex.RUnlock()
 	if found {
 		return sinfo, nil
@@ -85,6 +143,21 @@ func getStructInfo(st reflect.Type) (*structInfo, error) {
 	fieldsList := make([]fieldInfo, 0, n)
 	inlineMap := -1
 	inlineUnmarshalers := [][]int(nil)
+	defaults := false
+	addField := func(info fieldInfo) error {
+		for _, key := range append([]string{info.Key}, info.Aliases...) {
+			if _, found := fieldsMap[key]; found {
+				return errors.New("duplicated key '" + key + "' in struct " + st.String())
+			}
+		}
+		info.ID = len(fieldsList)
+		fieldsList = append(fieldsList, info)
+		fieldsMap[info.Key] = info
+		for _, alias := range info.Aliases {
+			fieldsMap[alias] = info
+		}
+		return nil
+	}
 	for i := 0; i != n; i++ {
 		field := st.Field(i)
 		if field.PkgPath != "" && !field.Anonymous {
@@ -93,10 +166,7 @@ func getStructInfo(st reflect.Type) (*structInfo, error) {
 
 		info := fieldInfo{Num: i}
 
-		tag := field.Tag.Get("yaml")
-		if tag == "" && strings.IndexByte(string(field.Tag), ':') < 0 {
-			tag = string(field.Tag)
-		}
+		tag, fromJSON := fieldTag(field, jsonTags)
 		if tag == "-" {
 			continue
 		}
@@ -105,19 +175,64 @@ func getStructInfo(st reflect.Type) (*structInfo, error) {
 		fields := strings.Split(tag, ",")
 		if len(fields) > 1 {
 			for _, flag := range fields[1:] {
-				switch flag {
-				case "omitempty":
+				name, value, hasValue := strings.Cut(flag, "=")
+				switch {
+				case flag == "omitempty":
 					info.OmitEmpty = true
-				case "flow":
+				case flag == "flow":
 					info.Flow = true
-				case "inline":
+				case flag == "inline":
 					inline = true
+				case flag == "required":
+					info.Required = true
+				case flag == "string":
+					info.String = isStringFlagType(field.Type)
+				case flag == "literal", flag == "folded", flag == "quoted", flag == "singlequoted":
+					if info.Style != yaml_ANY_SCALAR_STYLE {
+						return nil, errors.Errorf("conflicting style flag %q in tag %q of type %s", flag, tag, st)
+					}
+					info.Style = scalarStyleFlags[flag]
+				case name == "alias" && hasValue && value != "":
+					info.Aliases = append(info.Aliases, value)
+				case name == "default" && hasValue && value != "":
+					info.Default = value
+				case name == "comment" && hasValue && !fromJSON:
+					info.HeadComment = value
+				case name == "linecomment" && hasValue && !fromJSON:
+					info.LineComment = value
 				default:
+					if fromJSON {
+						// Ignore json flags we don't know about.
+						continue
+					}
 					return nil, errors.Errorf("unsupported flag %q in tag %q of type %s", flag, tag, st)
 				}
 			}
 			tag = fields[0]
 		}
+		if _, ok := field.Tag.Lookup("yaml"); jsonTags && !ok && tag == "" && field.Anonymous {
+			// Mimic encoding/json: embedded structs without a name
+			// are inlined.
+			ftype := field.Type
+			if ftype.Kind() == reflect.Ptr {
+				ftype = ftype.Elem()
+			}
+			switch {
+			case field.PkgPath != "" && field.Type.Kind() != reflect.Struct:
+				// Embedded pointers to unexported structs and other
+				// unexported types can't be set, so ignore them.
+				continue
+			case ftype.Kind() == reflect.Struct:
+				inline = true
+			}
+		}
+
+		if comment, ok := field.Tag.Lookup("comment"); ok {
+			info.HeadComment = comment
+		}
+		if comment, ok := field.Tag.Lookup("linecomment"); ok {
+			info.LineComment = comment
+		}
 
 		if inline {
 			switch field.Type.Kind() {
@@ -137,29 +252,26 @@ func getStructInfo(st reflect.Type) (*structInfo, error) {
 				if ftype.Kind() != reflect.Struct {
 					return nil, errors.New("option ,inline may only be used on a struct or map field")
 				}
-				if reflect.PtrTo(ftype).Implements(unmarshalerType) {
+				if pt := reflect.PtrTo(ftype); pt.Implements(unmarshalerType) || pt.Implements(unmarshalerContextType) {
 					inlineUnmarshalers = append(inlineUnmarshalers, []int{i})
 				} else {
-					si, err := getStructInfo(ftype)
+					si, err := getStructInfo(ftype, jsonTags)
 					if err != nil {
 						return nil, err
 					}
+					defaults = defaults || si.Defaults
 					for _, index := range si.InlineUnmarshalers {
 						inlineUnmarshalers = append(inlineUnmarshalers, append([]int{i}, index...))
 					}
 					for _, finfo := range si.FieldsList {
-						if _, ok := fieldsMap[finfo.Key]; ok {
-							msg := "duplicated key '" + finfo.Key + "' in struct " + st.String()
-							return nil, errors.New(msg)
-						}
 						if finfo.Inline == nil {
 							finfo.Inline = []int{i, finfo.Num}
 						} else {
 							finfo.Inline = append([]int{i}, finfo.Inline...)
 						}
-						finfo.ID = len(fieldsList)
-						fieldsMap[finfo.Key] = finfo
-						fieldsList = append(fieldsList, finfo)
+						if err := addField(finfo); err != nil {
+							return nil, err
+						}
 					}
 				}
 			default:
@@ -168,35 +280,109 @@ func getStructInfo(st reflect.Type) (*structInfo, error) {
 			continue
 		}
 
-		if tag != "" {
+		switch {
+		case tag != "":
 			info.Key = tag
-		} else {
+		case fromJSON:
+			// encoding/json uses the field name as is.
+			info.Key = field.Name
+		default:
 			info.Key = strings.ToLower(field.Name)
 		}
 
-		if _, found = fieldsMap[info.Key]; found {
-			msg := "duplicated key '" + info.Key + "' in struct " + st.String()
-			return nil, errors.New(msg)
+		if info.Default != "" || nestedDefaults(field.Type, jsonTags) != nil {
+			defaults = true
 		}
+		if err := addField(info); err != nil {
+			return nil, err
+		}
+	}
 
-		info.ID = len(fieldsList)
-		fieldsList = append(fieldsList, info)
-		fieldsMap[info.Key] = info
+	required := false
+	foldMap := make(map[string]fieldInfo, len(fieldsMap))
+	for _, info := range fieldsList {
+		required = required || info.Required
+		for _, key := range append([]string{info.Key}, info.Aliases...) {
+			key = strings.ToLower(key)
+			if _, found := foldMap[key]; !found {
+				foldMap[key] = info
+			}
+		}
 	}
 
 	sinfo = &structInfo{
 		FieldsMap:          fieldsMap,
 		FieldsList:         fieldsList,
+		FoldMap:            foldMap,
 		InlineMap:          inlineMap,
 		InlineUnmarshalers: inlineUnmarshalers,
+		Required:           required,
+		Defaults:           defaults,
 	}
 
 	fieldMapMutex.Lock()
-	structMap[st] = sinfo
+	structMap[key] = sinfo
 	fieldMapMutex.Unlock()
 	return sinfo, nil
 }
 
+var scalarStyleFlags = map[string]yaml_scalar_style_t{
+	"literal":      yaml_LITERAL_SCALAR_STYLE,
+	"folded":       yaml_FOLDED_SCALAR_STYLE,
+	"quoted":       yaml_DOUBLE_QUOTED_SCALAR_STYLE,
+	"singlequoted": yaml_SINGLE_QUOTED_SCALAR_STYLE,
+}
+
+// nestedDefaults returns struct info of the field if it is a nested
+// struct with default values.
+func nestedDefaults(field reflect.Type, jsonTags bool) *structInfo {
+	if field.Kind() != reflect.Struct {
+		return nil
+	}
+	if pt := reflect.PtrTo(field); pt.Implements(unmarshalerType) ||
+		pt.Implements(unmarshalerContextType) ||
+		pt.Implements(textUnmarshalerType) {
+		return nil
+	}
+	sinfo, err := getStructInfo(field, jsonTags)
+	if err != nil || !sinfo.Defaults {
+		return nil
+	}
+	return sinfo
+}
+
+// isStringFlagType returns true if the string flag applies to given type.
+func isStringFlagType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		// Durations are marshaled as strings anyway.
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Bool:
+		return true
+	default:
+		return false
+	}
+}
+
+// field returns the field matching given key.
+//
+// If fold is true, keys are matched case-insensitively when there is
+// no exact match.
+func (sinfo *structInfo) field(key string, fold bool) (fieldInfo, bool) {
+	info, ok := sinfo.FieldsMap[key]
+	if !ok && fold {
+		info, ok = sinfo.FoldMap[strings.ToLower(key)]
+	}
+	return info, ok
+}
+
 func isHashable(val reflect.Value) bool {
 	var check func(t reflect.Type) bool
 	check = func(t reflect.Type) bool {
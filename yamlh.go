@@ -553,7 +553,8 @@ type yaml_parser_t struct {
 
 	error yaml_error_type_t // Error type.
 
-	problem string // Error description.
+	problem     string // Error description.
+	problem_err error  // [yaml] Underlying error, if any (e.g. exceeded limit).
 
 	// The byte about which the problem occurred.
 	problem_offset int
@@ -572,6 +573,8 @@ type yaml_parser_t struct {
 	input_reader io.Reader // File input data.
 	input        []byte    // String input data.
 	input_pos    int
+	input_read   int // [yaml] The number of bytes read from the input.
+	max_bytes    int // [yaml] The maximum number of bytes to read, 0 means no limit.
 
 	eof bool // EOF flag
 
@@ -607,6 +610,7 @@ type yaml_parser_t struct {
 	stream_end_produced   bool // Have we reached the end of the input stream?
 
 	flow_level int // The number of unclosed '[' and '{' indicators.
+	max_depth  int // [yaml] The maximum flow level and indentation stack size.
 
 	tokens          []yaml_token_t // The tokens queue.
 	tokens_head     int            // The head of the tokens queue.